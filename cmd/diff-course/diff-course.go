@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+func getRevision(courseID string, rev string) rnd.Course {
+	if rev == "current" {
+		return rnd.GetCourse(courseID)
+	}
+	if rev == "initial" {
+		rev = ""
+	}
+	return rnd.GetCourseRevision(courseID, rev)
+}
+
+func main() {
+
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("usage: diff-course [-home dir] <courseID> [<revision> <revision>]")
+		os.Exit(2)
+	}
+	courseID := flag.Arg(0)
+
+	// with no revisions given just list them
+	if flag.NArg() < 3 {
+		for _, c := range rnd.GetCourseRevisions(courseID) {
+			eff := c.Effective
+			if eff == "" {
+				eff = "initial"
+			}
+			fmt.Println(eff)
+		}
+		return
+	}
+
+	a := getRevision(courseID, flag.Arg(1))
+	b := getRevision(courseID, flag.Arg(2))
+
+	changes := rnd.DiffCourses(a, b)
+	for _, cc := range changes {
+		fmt.Println(cc)
+	}
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -17,6 +18,9 @@ var templates embed.FS
 
 var crs rnd.Course
 
+// the layout as loaded, archived as a revision on the first save
+var orig rnd.Course
+
 func homeHandler(w http.ResponseWriter, r *http.Request) {
 	tmpl := template.Must(template.ParseFS(templates, "templates/landing.html"))
 	tmpl.Execute(w, nil)
@@ -56,6 +60,90 @@ func movepointHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	for _, h := range crs.Holes {
+		for _, f := range h.Fairways {
+			for k, w := range f.Path {
+				if rnd.WaypointName(h.ID, f.Tee, f.Pin, k) == name {
+					w[0] = lat
+					w[1] = lon
+
+					crs.DrawSummary()
+					return
+				}
+			}
+		}
+	}
+
+	for i, h := range crs.Holes {
+		for j, d := range h.DropZones {
+			if h.ID+"_"+d.ID == name {
+				crs.Holes[i].DropZones[j].Loc = rnd.Loc{lat, lon}
+				crs.DrawSummary()
+				return
+			}
+		}
+		for j, m := range h.Mandos {
+			if h.ID+"_"+m.ID == name && len(m.DropZone) >= 2 {
+				crs.Holes[i].Mandos[j].DropZone = rnd.Loc{lat, lon}
+				crs.DrawSummary()
+				return
+			}
+		}
+	}
+
+	fmt.Println("Shouldn't be here")
+
+}
+
+func newWaypointHandler(w http.ResponseWriter, r *http.Request) {
+
+	fmt.Println(r.URL)
+
+	name_s := r.URL.Query()["name"]
+	name := name_s[0]
+	lat_s := r.URL.Query()["lat"]
+	lat, _ := strconv.ParseFloat(lat_s[0], 64)
+	lon_s := r.URL.Query()["lon"]
+	lon, _ := strconv.ParseFloat(lon_s[0], 64)
+
+	for i, h := range crs.Holes {
+		for _, t := range h.Tees {
+			for _, p := range h.Pins {
+				if h.ID+"_"+t.ID+"->"+p.ID == name {
+					crs.Holes[i].InsertWaypoint(t.ID, p.ID, rnd.Loc{lat, lon})
+					crs.DrawSummary()
+					return
+				}
+			}
+		}
+	}
+
+	fmt.Println("Shouldn't be here")
+
+}
+
+func deleteWaypointHandler(w http.ResponseWriter, r *http.Request) {
+
+	fmt.Println(r.URL)
+
+	name_s := r.URL.Query()["name"]
+	name := name_s[0]
+
+	for i, h := range crs.Holes {
+		for _, f := range h.Fairways {
+			for k := range f.Path {
+				if rnd.WaypointName(h.ID, f.Tee, f.Pin, k) == name {
+					wps := append([]rnd.Loc{}, f.Path[:k]...)
+					wps = append(wps, f.Path[k+1:]...)
+					crs.Holes[i].SetWaypoints(f.Tee, f.Pin, wps)
+
+					crs.DrawSummary()
+					return
+				}
+			}
+		}
+	}
+
 	fmt.Println("Shouldn't be here")
 
 }
@@ -105,6 +193,7 @@ func newPOIHandler(w http.ResponseWriter, r *http.Request) {
 	tee := tee_s[0]
 	pin_s := r.URL.Query()["pin"]
 	pin := pin_s[0]
+	drop := r.URL.Query().Get("drop")
 	lat_s := r.URL.Query()["lat"]
 	lat, _ := strconv.ParseFloat(lat_s[0], 64)
 	lon_s := r.URL.Query()["lon"]
@@ -124,7 +213,17 @@ func newPOIHandler(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	if tee != "" {
+	if drop != "" && tee == "" && pin == "" {
+		for i, h := range crs.Holes {
+			if h.ID == hole {
+				crs.Holes[i].DropZones = append(crs.Holes[i].DropZones, rnd.DropZone{
+					ID:  drop,
+					Loc: rnd.Loc{lat, lon},
+				})
+				break
+			}
+		}
+	} else if tee != "" {
 		for i, h := range crs.Holes {
 			if h.ID == hole {
 
@@ -137,7 +236,7 @@ func newPOIHandler(w http.ResponseWriter, r *http.Request) {
 					crs.Holes[i].Pars = append(crs.Holes[i].Pars, rnd.Par{
 						Tee: tee,
 						Pin: p.ID,
-						Par: 3,
+						Par: crs.Holes[i].SuggestPar(tee, p.ID, rnd.HoleStats{}),
 					})
 				}
 
@@ -157,7 +256,7 @@ func newPOIHandler(w http.ResponseWriter, r *http.Request) {
 					crs.Holes[i].Pars = append(crs.Holes[i].Pars, rnd.Par{
 						Tee: t.ID,
 						Pin: pin,
-						Par: 3,
+						Par: crs.Holes[i].SuggestPar(t.ID, pin, rnd.HoleStats{}),
 					})
 				}
 
@@ -170,42 +269,115 @@ func newPOIHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func resolveHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	i, err := strconv.Atoi(r.URL.Query().Get("i"))
+	if err != nil || i < 0 || i >= len(crs.Conflicts) {
+		http.Error(w, "no conflict "+r.URL.Query().Get("i"), http.StatusBadRequest)
+		return
+	}
+	crs.ResolveConflict(i, r.URL.Query().Get("take") == "theirs")
+	crs.DrawSummary()
+}
+
+func newRotationHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	if err := crs.AddRotation(r.URL.Query().Get("from")); err != nil {
+		fmt.Println(err)
+	}
+	crs.DrawSummary()
+}
+
+func rotationPinHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	q := r.URL.Query()
+	i, _ := strconv.Atoi(q.Get("i"))
+	crs.SetRotationPin(i, q.Get("hole"), q.Get("pin"))
+	crs.DrawSummary()
+}
+
+func rotationDatesHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	q := r.URL.Query()
+	i, _ := strconv.Atoi(q.Get("i"))
+	crs.SetRotationDates(i, q.Get("from"), q.Get("to"))
+	crs.DrawSummary()
+}
+
+func deleteRotationHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	i, _ := strconv.Atoi(r.URL.Query().Get("i"))
+	crs.DeleteRotation(i)
+	crs.DrawSummary()
+}
+
+func suggestParsHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(r.URL)
+
+	// only the missing pars unless asked to replace the ones set by hand
+	crs.SuggestPars(r.FormValue("overwrite") != "")
+	crs.DrawSummary()
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
 func newparHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Println(r.URL)
 
-	row_s := r.URL.Query()["row"]
-	row, _ := strconv.Atoi(row_s[0])
-	par_s := r.URL.Query()["par"]
-	par, _ := strconv.Atoi(par_s[0])
+	row, err := strconv.Atoi(r.URL.Query().Get("row"))
+	if err != nil {
+		http.Error(w, "no row "+r.URL.Query().Get("row"), http.StatusBadRequest)
+		return
+	}
+	par, err := strconv.Atoi(r.URL.Query().Get("par"))
+	if err != nil {
+		http.Error(w, "bad par "+r.URL.Query().Get("par"), http.StatusBadRequest)
+		return
+	}
 
-	idx := 0
-	for i, h := range crs.Holes {
-		for j, _ := range h.Tees {
-			for k, _ := range h.Pins {
-				if idx == row {
-					crs.Holes[i].Pars[j*len(h.Pins)+k].Par = par
-					crs.DrawSummary()
-					return
-				} else {
-					idx++
-				}
-			}
-		}
+	if !crs.SetParRow(row, par) {
+		http.Error(w, "no row "+r.URL.Query().Get("row"), http.StatusBadRequest)
+		return
 	}
+	crs.DrawSummary()
 }
 
 func saveCourseHandler(w http.ResponseWriter, r *http.Request) {
-	crs.SaveCourseJSON()
+	for _, warn := range crs.Validate() {
+		fmt.Println("Warning: " + warn)
+	}
+
+	// each save is a revision of its own, so rounds played between two saves
+	// on the same day keep the layout they were played on
+	crs.Effective = rnd.EffectiveNow()
+	if orig.Effective != crs.Effective {
+		orig.SaveCourseRevision()
+	}
+	crs.SaveCourseRevision()
+	crs.SaveCourse()
+	orig = rnd.GetCourse(crs.ID)
 	fmt.Println("Course saved.")
 	http.Redirect(w, r, "/", http.StatusPermanentRedirect)
 }
 
 func main() {
 
-	in := os.Args[1]
-	// in := "kit_carson.json"
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("usage: edit-course [-home dir] <courseID>")
+		os.Exit(2)
+	}
+	courseID := flag.Arg(0)
 
-	crs = rnd.ParseCourseJSON(in)
+	crs = rnd.GetCourse(courseID)
+	orig = rnd.GetCourse(courseID)
+	if crs.ID == "" {
+		crs.ID = courseID
+	}
 	crs.DrawSummary()
 
 	// make mapbox representation of course
@@ -214,11 +386,25 @@ func main() {
 	// handle drags
 	http.HandleFunc("/movepoint", movepointHandler)
 
+	// handle fairway waypoints
+	http.HandleFunc("/newwaypoint", newWaypointHandler)
+	http.HandleFunc("/deletewaypoint", deleteWaypointHandler)
+
 	// handle renames
 	http.HandleFunc("/text", textHandler)
 
 	// handle new pars
 	http.HandleFunc("/newpar", newparHandler)
+	http.HandleFunc("/suggestpars", suggestParsHandler)
+
+	// handle the pin rotation calendar
+	http.HandleFunc("/newrotation", newRotationHandler)
+	http.HandleFunc("/rotationpin", rotationPinHandler)
+	http.HandleFunc("/rotationdates", rotationDatesHandler)
+	http.HandleFunc("/deleterotation", deleteRotationHandler)
+
+	// handle merge conflicts
+	http.HandleFunc("/resolve", resolveHandler)
 
 	// handle new clicks
 	http.HandleFunc("/newPOI", newPOIHandler)
@@ -227,7 +413,7 @@ func main() {
 	http.HandleFunc("/save", saveCourseHandler)
 
 	// serve local files as if they were in /data
-	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir("."))))
+	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir(rnd.VisDir()))))
 
 	// serve up for local use
 	fmt.Println("Edit the course at 0.0.0.0:8081")
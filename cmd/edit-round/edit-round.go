@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 	"fmt"
 	"html/template"
 	"net/http"
@@ -106,10 +107,15 @@ func main() {
 	// ts, fID := rnd.GetRoundRaw(ts_csv, rec_gpx)
 
 	// rt = rnd.GetRound(ts, fID)
-	cwd, _ := os.Getwd()
-	path := filepath.Base(cwd)
-	fmt.Println(path)
-	rt = rnd.ReadRoundCSV(path + ".csv")
+	// the round id defaults to the name of the round directory we're in
+	flag.Parse()
+	roundID := flag.Arg(0)
+	if roundID == "" {
+		cwd, _ := os.Getwd()
+		roundID = filepath.Base(cwd)
+	}
+	fmt.Println(roundID)
+	rt = rnd.LoadRound(roundID)
 
 	rt.DrawSummary()
 
@@ -132,7 +138,7 @@ func main() {
 	http.HandleFunc("/addpoint", addHandler)
 
 	// // serve local files as if they were in /data
-	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir("."))))
+	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir(rnd.VisDir()))))
 
 	// // serve local files as if they were in /coursedata
 	// http.Handle("/coursedata/", http.StripPrefix("/coursedata/", http.FileServer(http.FS(courses))))
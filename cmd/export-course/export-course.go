@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+func main() {
+
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("usage: export-course [-home dir] <courseID|course.json> [gpx|kml]")
+		os.Exit(2)
+	}
+
+	crs := rnd.LoadCourse(flag.Arg(0))
+	if crs.ID == "" {
+		os.Exit(1)
+	}
+
+	format := "all"
+	if flag.NArg() > 1 {
+		format = flag.Arg(1)
+	}
+
+	switch format {
+	case "gpx":
+		crs.WriteGPX()
+	case "kml":
+		crs.WriteKML()
+	case "all":
+		crs.WriteGPX()
+		crs.WriteKML()
+	default:
+		fmt.Println("unknown format " + format)
+		os.Exit(2)
+	}
+	fmt.Println("Exported to " + filepath.Join(rnd.DataHome(), "exports"))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+type patternList []string
+
+func (p *patternList) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *patternList) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
+func main() {
+
+	np := rnd.DefaultNamePatterns
+
+	var patterns patternList
+	flag.Var(&patterns, "pattern", "regexp with hole, kind and id groups for placemark names, may be repeated")
+	teeWords := flag.String("tee", strings.Join(np.TeeWords, ","), "comma separated words that mark a tee")
+	pinWords := flag.String("pin", strings.Join(np.PinWords, ","), "comma separated words that mark a pin")
+	dropWords := flag.String("drop", strings.Join(np.DropWords, ","), "comma separated words that mark a drop zone")
+	flag.StringVar(&np.TeeID, "tee-id", np.TeeID, "tee id when a name has none")
+	flag.StringVar(&np.PinID, "pin-id", np.PinID, "pin id when a name has none")
+	force := flag.Bool("force", false, "replace a course already in the store, keeping the old layout as a revision")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: import-course [flags] <file.kml|file.kmz|file.geojson> <courseID> <courseName>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	in := flag.Arg(0)
+	courseID := flag.Arg(1)
+	courseName := flag.Arg(2)
+	if rnd.CourseExists(courseID) && !*force {
+		log.Fatal(courseID + " is already in the store, use -force to replace it")
+	}
+
+	if len(patterns) > 0 {
+		np.Patterns = patterns
+	}
+	np.TeeWords = strings.Split(*teeWords, ",")
+	np.PinWords = strings.Split(*pinWords, ",")
+	np.DropWords = strings.Split(*dropWords, ",")
+
+	pms := rnd.ParsePlacemarks(in)
+	crs, unmatched := rnd.PlacemarksToCourse(courseID, courseName, pms, np)
+	for _, u := range unmatched {
+		fmt.Println("Skipped placemark: " + u)
+	}
+
+	for _, w := range crs.Validate() {
+		fmt.Println("Warning: " + w)
+	}
+
+	crs.ReplaceCourse()
+	fmt.Printf("Saved %s with %d holes\n", crs.ID, len(crs.Holes))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+// Moves courses and the disc catalog into the data home, e.g. from the data
+// directory of a checkout that kept them there
+func main() {
+	force := flag.Bool("force", false, "replace courses and the disc catalog already in the store")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: import-data [-home dir] [-force] <dir>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	copied, err := rnd.ImportData(flag.Arg(0), *force)
+	for _, c := range copied {
+		fmt.Println("Imported " + c)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d imported into %s\n", len(copied), rnd.DataHome())
+}
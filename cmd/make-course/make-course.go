@@ -2,13 +2,14 @@ package main
 
 import (
 	"encoding/csv"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
@@ -18,23 +19,55 @@ type csvstr struct {
 	variation string
 	lat       float64
 	lon       float64
+	alt       float64
+	hasAlt    bool
+	par       int
 }
 type csvFile []csvstr
 
+// Column of each header name, lower cased
+func headerIndex(header []string) map[string]int {
+	idx := map[string]int{}
+	for i, h := range header {
+		idx[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	return idx
+}
+
+// Field of a line by header name, empty if the file doesn't have it
+func field(line []string, idx map[string]int, names ...string) string {
+	for _, n := range names {
+		if i, ok := idx[n]; ok && i < len(line) {
+			return strings.TrimSpace(line[i])
+		}
+	}
+	return ""
+}
+
+// Reads tees.csv or pins.csv. Columns are found by their header: hole,
+// variation, lat and lon, plus an optional surveyed alt in meters and an
+// optional par.
 func readCSV(fname string) csvFile {
 	var l []csvstr
 
 	f, err := os.Open(fname)
 	if err != nil {
-		fmt.Println(err)
+		log.Fatal(err)
 	}
 	defer f.Close()
 
 	r := csv.NewReader(f)
-	_, err = r.Read() // Burn the header line
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
 	if err != nil {
 		log.Fatal(err)
 	}
+	idx := headerIndex(header)
+	for _, h := range []string{"hole", "variation", "lat", "lon"} {
+		if _, ok := idx[h]; !ok {
+			log.Fatalf("%s: no %s column", fname, h)
+		}
+	}
 
 	for {
 		line, err := r.Read()
@@ -45,19 +78,35 @@ func readCSV(fname string) csvFile {
 			log.Fatal(err)
 		}
 
-		lat, _ := strconv.ParseFloat(line[2], 64)
-		lon, _ := strconv.ParseFloat(line[3], 64)
-		l = append(l, csvstr{
-			hole:      line[0],
-			variation: line[1],
+		lat, _ := strconv.ParseFloat(field(line, idx, "lat"), 64)
+		lon, _ := strconv.ParseFloat(field(line, idx, "lon"), 64)
+		c := csvstr{
+			hole:      field(line, idx, "hole"),
+			variation: field(line, idx, "variation"),
 			lat:       lat,
 			lon:       lon,
-		})
+		}
+
+		if a := field(line, idx, "alt"); a != "" {
+			c.alt, _ = strconv.ParseFloat(a, 64)
+			c.hasAlt = true
+		}
+		if p := field(line, idx, "par"); p != "" {
+			c.par, _ = strconv.Atoi(p)
+		}
+		l = append(l, c)
 	}
 
 	return csvFile(l)
 }
 
+func (c csvstr) loc() rnd.Loc {
+	if c.hasAlt {
+		return rnd.Loc{c.lat, c.lon, c.alt}
+	}
+	return rnd.Loc{c.lat, c.lon}
+}
+
 func makeCourseJSON(id string, name string, tees csvFile, pins csvFile) rnd.Course {
 
 	crsLoc := rnd.Loc{tees[0].lat, tees[0].lon}
@@ -65,7 +114,6 @@ func makeCourseJSON(id string, name string, tees csvFile, pins csvFile) rnd.Cour
 	cur_hole := ""
 	var ts []rnd.Tee
 	var ps []rnd.Pin
-	var pars []rnd.Par
 	var holes []rnd.Hole
 	for i, t := range tees {
 		if t.hole != cur_hole {
@@ -75,63 +123,98 @@ func makeCourseJSON(id string, name string, tees csvFile, pins csvFile) rnd.Cour
 					ID:   cur_hole,
 					Tees: ts,
 					Pins: ps,
-					Pars: pars,
 				})
 			}
 
 			cur_hole = t.hole
 			ts = nil
 			ps = nil
-			pars = nil
+
+			// the pins once per hole, however many tees it has
+			for _, p := range pins {
+				if p.hole == t.hole {
+					ps = append(ps, rnd.Pin{
+						ID:  p.variation,
+						Loc: p.loc(),
+					})
+				}
+			}
 		}
 
 		ts = append(ts, rnd.Tee{
 			ID:  t.variation,
-			Loc: rnd.Loc{t.lat, t.lon},
+			Loc: t.loc(),
 		})
-
-		for _, p := range pins {
-			if p.hole == t.hole {
-				ps = append(ps, rnd.Pin{
-					ID:  p.variation,
-					Loc: rnd.Loc{p.lat, p.lon},
-				})
-
-				pars = append(pars, rnd.Par{
-					Tee: t.variation,
-					Pin: p.variation,
-					Par: 3,
-				})
-
-			}
-		}
 	}
 	holes = append(holes, rnd.Hole{
 		ID:   cur_hole,
 		Tees: ts,
 		Pins: ps,
-		Pars: pars,
 	})
 
-	return rnd.Course{
+	crs := rnd.Course{
 		ID:    id,
 		Name:  name,
 		Loc:   crsLoc,
 		Holes: holes,
 	}
+	crs.SuggestPars(false)
+	setPars(&crs, tees, pins)
+
+	return crs
 }
 
-func main() {
+// Overrides the suggested pars with any given in tees.csv and pins.csv. A
+// par on a tee counts for every pin of its hole and a par on a pin for every
+// tee, with the pin's winning where both are given.
+func setPars(crs *rnd.Course, tees csvFile, pins csvFile) {
+	for _, h := range crs.Holes {
+		for k, p := range h.Pars {
+			for _, t := range tees {
+				if t.hole == h.ID && t.variation == p.Tee && t.par > 0 {
+					h.Pars[k].Par = t.par
+				}
+			}
+			for _, q := range pins {
+				if q.hole == h.ID && q.variation == p.Pin && q.par > 0 {
+					h.Pars[k].Par = q.par
+				}
+			}
+		}
+	}
+}
 
-	tees := readCSV("tees.csv")
-	pins := readCSV("pins.csv")
+func main() {
+	first := flag.Int("first", 1, "number of the first hole walked in a survey")
+	force := flag.Bool("force", false, "replace a course already in the store, keeping the old layout as a revision")
 
-	courseID := os.Args[1]
-	courseName := os.Args[2]
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Println("usage: make-course [-home dir] [-first n] [-force] <courseID> <courseName> [walk.csv|waypoints.gpx]")
+		os.Exit(2)
+	}
 
-	cJSON := makeCourseJSON(courseID, courseName, tees, pins)
+	courseID := flag.Arg(0)
+	courseName := flag.Arg(1)
+	if rnd.CourseExists(courseID) && !*force {
+		log.Fatal(courseID + " is already in the store, use -force to replace it")
+	}
 
-	file, _ := json.MarshalIndent(cJSON, "", "	")
+	// tees.csv and pins.csv in the current directory, or a surveyed walk
+	var tees, pins csvFile
+	switch survey := flag.Arg(2); strings.ToLower(filepath.Ext(survey)) {
+	case "":
+		tees = readCSV("tees.csv")
+		pins = readCSV("pins.csv")
+	case ".gpx":
+		tees, pins = groupWalk(readGPXWaypoints(survey), *first)
+	default:
+		tees, pins = groupWalk(readTaggedCSV(survey), *first)
+	}
+	if len(tees) == 0 {
+		log.Fatal("no tees found")
+	}
 
-	_ = ioutil.WriteFile(courseID+".json", file, 0644)
+	cJSON := makeCourseJSON(courseID, courseName, tees, pins)
+	cJSON.ReplaceCourse()
 }
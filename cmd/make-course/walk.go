@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// A point tagged as a tee or pin while walking the course
+type walkPt struct {
+	tag  string // "t" or "p"
+	hole string // from the tag if it gave one, like t5 or "Pin 5"
+	c    csvstr
+}
+
+// Tee or pin from a tag like t, p, t5, Tee 5, P12 or basket. Anything else
+// isn't one.
+func parseTag(s string) (string, string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	var tag string
+	for _, pre := range []string{"tee", "t", "pin", "basket", "p"} {
+		if strings.HasPrefix(s, pre) {
+			tag = pre[:1]
+			s = strings.TrimSpace(strings.TrimPrefix(s, pre))
+			break
+		}
+	}
+	switch tag {
+	case "":
+		return "", "", false
+	case "b":
+		tag = "p"
+	}
+	if s == "" {
+		return tag, "", true
+	}
+	if _, err := strconv.Atoi(s); err != nil {
+		return "", "", false
+	}
+	return tag, s, true
+}
+
+// Reads the tagged points of a raw round, like the ones in misc/crsCSVs,
+// where a t or p in the tag column (sdf in the older files) marks a tee or
+// pin
+func readTaggedCSV(fname string) []walkPt {
+	var pts []walkPt
+
+	f, err := os.Open(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		log.Fatal(err)
+	}
+	idx := headerIndex(header)
+	if _, ok := idx["tag"]; !ok {
+		if _, ok := idx["sdf"]; !ok {
+			log.Fatalf("%s: no tag column", fname)
+		}
+	}
+
+	for {
+		line, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tag, hole, ok := parseTag(field(line, idx, "tag", "sdf"))
+		if !ok {
+			continue
+		}
+		lat, _ := strconv.ParseFloat(field(line, idx, "lat"), 64)
+		lon, _ := strconv.ParseFloat(field(line, idx, "lon"), 64)
+		c := csvstr{lat: lat, lon: lon}
+		if a := field(line, idx, "alt"); a != "" {
+			c.alt, _ = strconv.ParseFloat(a, 64)
+			c.hasAlt = true
+		}
+		pts = append(pts, walkPt{tag: tag, hole: hole, c: c})
+	}
+
+	return pts
+}
+
+// Reads GPX waypoints named or typed as tees and pins. They're walked in
+// time order if they all have times and in file order otherwise.
+func readGPXWaypoints(fname string) []walkPt {
+	g, err := gpx.ParseFile(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	wpts := g.Waypoints
+	timed := len(wpts) > 0
+	for _, w := range wpts {
+		timed = timed && !w.Timestamp.IsZero()
+	}
+	if timed {
+		sort.SliceStable(wpts, func(i, j int) bool {
+			return wpts[i].Timestamp.Before(wpts[j].Timestamp)
+		})
+	}
+
+	var pts []walkPt
+	for _, w := range wpts {
+		tag, hole, ok := parseTag(w.Name)
+		if !ok {
+			tag, hole, ok = parseTag(w.Type)
+		}
+		if !ok {
+			continue
+		}
+		c := csvstr{lat: w.Latitude, lon: w.Longitude}
+		if w.Elevation.NotNull() {
+			c.alt = w.Elevation.Value()
+			c.hasAlt = true
+		}
+		pts = append(pts, walkPt{tag: tag, hole: hole, c: c})
+	}
+
+	return pts
+}
+
+// Variation names for n tees or pins of a hole: reg or x for one, A, B, ...
+// for more
+func variations(n int, single string) []string {
+	if n == 1 {
+		return []string{single}
+	}
+	var v []string
+	for i := 0; i < n; i++ {
+		v = append(v, string(rune('A'+i)))
+	}
+	return v
+}
+
+// Groups a walk into holes. A tee after a pin starts the next hole, and tees
+// or pins in a row are alternates of the same hole. Holes are numbered from
+// first in walking order unless a tag gives the number, and a tag numbering a
+// different hole starts that one.
+func groupWalk(pts []walkPt, first int) (csvFile, csvFile) {
+	type hole struct {
+		id   string
+		tees []csvstr
+		pins []csvstr
+	}
+	var holes []*hole
+
+	next := first
+	prev := ""
+	for _, pt := range pts {
+		newHole := len(holes) == 0 || (pt.tag == "t" && prev == "p")
+		if !newHole && pt.hole != "" && pt.hole != holes[len(holes)-1].id {
+			newHole = true
+		}
+		if newHole {
+			id := pt.hole
+			if id == "" {
+				id = strconv.Itoa(next)
+			}
+			if n, err := strconv.Atoi(id); err == nil {
+				next = n + 1
+			}
+			holes = append(holes, &hole{id: id})
+		}
+		h := holes[len(holes)-1]
+		if pt.tag == "t" {
+			h.tees = append(h.tees, pt.c)
+		} else {
+			h.pins = append(h.pins, pt.c)
+		}
+		prev = pt.tag
+	}
+
+	var tees, pins csvFile
+	for _, h := range holes {
+		if len(h.tees) == 0 || len(h.pins) == 0 {
+			fmt.Printf("hole %s has %d tees and %d pins, skipping\n", h.id, len(h.tees), len(h.pins))
+			continue
+		}
+		for i, v := range variations(len(h.tees), "reg") {
+			t := h.tees[i]
+			t.hole, t.variation = h.id, v
+			tees = append(tees, t)
+		}
+		for i, v := range variations(len(h.pins), "x") {
+			p := h.pins[i]
+			p.hole, p.variation = h.id, v
+			pins = append(pins, p)
+		}
+	}
+
+	return tees, pins
+}
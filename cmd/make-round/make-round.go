@@ -1,11 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"embed"
+	"flag"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
@@ -92,18 +98,76 @@ func snapHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// Asks which course was played when more than one is close by
+func pickCourse(cands []rnd.CourseCandidate) rnd.Course {
+	if len(cands) == 0 {
+		log.Fatal("No course found near the start of the round")
+	}
+	if len(cands) == 1 {
+		return cands[0].Course
+	}
+
+	fmt.Println("Which course was played?")
+	for i, c := range cands {
+		inside := ""
+		if c.Inside {
+			inside = ", on course"
+		}
+		fmt.Printf("  %d) %s (score %.2f, %.0f m away%s)\n", i+1, c.Course.Name, c.Score, c.Dist, inside)
+	}
+	fmt.Print("[1]: ")
+
+	in, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	i, err := strconv.Atoi(strings.TrimSpace(in))
+	if err != nil || i < 1 || i > len(cands) {
+		i = 1
+	}
+	return cands[i-1].Course
+}
+
 func main() {
 	// in := os.Args[1]
 	// matches, _ := filepath.Glob("./*_raw.csv")
 	// m := matches[0]
 	// fID := m[:len(m)-8]
 
+	laps := flag.Bool("laps", false, "with a .fit file and no ts.csv, take each lap button press as a throw")
+	stops := flag.Bool("stops", false, "with no ts.csv, take each place the track stops as a throw")
+
+	flag.Parse()
 	ts_csv := "ts.csv"
 	rec_gpx := "rec.gpx"
-
-	ts, fID := rnd.GetRoundRaw(ts_csv, rec_gpx)
-
-	rt = rnd.GetRound(ts, fID)
+	if flag.NArg() >= 2 {
+		ts_csv = flag.Arg(0)
+		rec_gpx = flag.Arg(1)
+	}
+
+	// a GPX file on its own has the throws as waypoints, a FIT file its lap
+	// presses, and either can have them found from where the track stops
+	var ts rnd.Stamps
+	var fID string
+	if flag.NArg() == 1 {
+		rec_gpx = flag.Arg(0)
+	}
+	_, err := os.Stat(ts_csv)
+	switch {
+	case *stops:
+		ts, fID = rnd.GetRoundStops(rec_gpx)
+	case flag.NArg() == 1 && strings.EqualFold(filepath.Ext(rec_gpx), ".fit") && err != nil:
+		if !*laps {
+			log.Fatal("no " + ts_csv + ", use -laps to take lap presses as throws or -stops to find them from the track")
+		}
+		ts, fID = rnd.GetRoundFIT(rec_gpx)
+	case flag.NArg() == 1 && strings.EqualFold(filepath.Ext(rec_gpx), ".gpx"):
+		ts, fID = rnd.GetRoundGPX(rec_gpx)
+	case err != nil:
+		log.Fatal("no " + ts_csv + ", use -stops to find the throws from the track")
+	default:
+		ts, fID = rnd.GetRoundRaw(ts_csv, rec_gpx)
+	}
+
+	crs := pickCourse(rnd.InferCourses(ts[0].Loc))
+	rt = rnd.GetRoundOnCourse(ts, fID, crs)
 	rt.DrawSummary()
 
 	// // make mapbox representation of course
@@ -125,7 +189,7 @@ func main() {
 	http.HandleFunc("/addpoint", addHandler)
 
 	// // serve local files as if they were in /data
-	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir("."))))
+	http.Handle("/data/", http.StripPrefix("/data/", http.FileServer(http.Dir(rnd.VisDir()))))
 
 	// // serve local files as if they were in /coursedata
 	// http.Handle("/coursedata/", http.StripPrefix("/coursedata/", http.FileServer(http.FS(courses))))
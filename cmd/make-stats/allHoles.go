@@ -1,30 +1,32 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
-	"math"
-	"os"
-	"path/filepath"
 	"strconv"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
 
 type HoleRow struct {
-	Date      string
-	Course    string
-	Round     string
-	Hole      string
-	Par       int
-	Tee       string
-	Pin       string
-	Length    float64
-	DriveDist float64
-	MakeDist  float64
-	Score     int
-	Res       int
-	ResName   string
+	Date          string
+	Course        string
+	Round         string
+	Hole          string
+	Par           int
+	Tee           string
+	Pin           string
+	Length        float64
+	Elev          float64
+	PlaysLike     float64
+	DriveDist     float64
+	MakeDist      float64
+	Score         int
+	Res           int
+	ResName       string
+	Penalties     int
+	Mandos        int
+	MissedMandos  int
+	MandoMissRate float64
 }
 type AllHoles []HoleRow
 
@@ -38,11 +40,17 @@ func (t HoleRow) asStrings() []string {
 		t.Tee,
 		t.Pin,
 		fmt.Sprintf("%f", t.Length),
+		fmt.Sprintf("%f", t.Elev),
+		fmt.Sprintf("%f", t.PlaysLike),
 		fmt.Sprintf("%f", t.DriveDist),
 		fmt.Sprintf("%f", t.MakeDist),
 		strconv.Itoa(t.Score),
 		strconv.Itoa(t.Res),
 		t.ResName,
+		strconv.Itoa(t.Penalties),
+		strconv.Itoa(t.Mandos),
+		strconv.Itoa(t.MissedMandos),
+		fmt.Sprintf("%f", t.MandoMissRate),
 	}
 }
 
@@ -55,12 +63,18 @@ func (a AllHoles) GetHeader() []string {
 		"Par",
 		"Tee",
 		"Pin",
-		"Length",
-		"DriveDist",
-		"MakeDist",
+		rnd.WithUnits("Length"),
+		rnd.WithUnits("Elev"),
+		rnd.WithUnits("PlaysLike"),
+		rnd.WithUnits("DriveDist"),
+		rnd.WithUnits("MakeDist"),
 		"Score",
 		"Result",
 		"ResultLabel",
+		"Penalties",
+		"Mandos",
+		"MissedMandos",
+		"MandoMissRate",
 	}
 }
 
@@ -91,6 +105,7 @@ func GetRoundHoles(rd rnd.Round) AllHoles {
 
 	cur_hole := ""
 	shot_num := 1
+	missed := map[string]bool{}
 	for i, r := range rd.Data {
 
 		if r.HoleID != cur_hole {
@@ -108,24 +123,34 @@ func GetRoundHoles(rd rnd.Round) AllHoles {
 				Par:       h.Par(t.ID, p.ID),
 				Tee:       t.ID,
 				Pin:       p.ID,
-				Length:    math.Round(3.281 * rnd.Dist(t.Loc, p.Loc)),
-				DriveDist: math.Round(3.281 * rnd.Dist(t.Loc, nr.Loc())),
+				Length:    rnd.InUnits(h.Length(t.ID, p.ID)),
+				Elev:      rnd.InUnits(h.Elevation(t.ID, p.ID)),
+				PlaysLike: rnd.InUnits(h.PlaysLike(t.ID, p.ID)),
+				DriveDist: rnd.InUnits(rnd.Dist(t.Loc, nr.Loc())),
 				MakeDist:  0.0,
 				Score:     0,
 				Res:       0,
-				ResName:   ""})
+				ResName:   "",
+				Mandos:    len(h.Mandos)})
 
 			cur_hole = r.HoleID
 			shot_num = 1
+			missed = map[string]bool{}
 		} else {
 			shot_num++
 		}
+		AH[len(AH)-1].Penalties += r.Penalty
+		// a re-thrown mando missed again is still one mando missed
+		if r.Mando != "" && !missed[r.Mando] {
+			missed[r.Mando] = true
+			AH[len(AH)-1].MissedMandos++
+		}
 
 		if r.Disc == "BASKET" {
 			// then we finish writing this guy
 			pr := rd.Data[i-1]
-			AH[len(AH)-1].MakeDist = math.Round(3.281 * rnd.Dist(pr.Loc(), p.Loc))
-			AH[len(AH)-1].Score = shot_num - 1
+			AH[len(AH)-1].MakeDist = rnd.InUnits(rnd.Dist(pr.Loc(), p.Loc))
+			AH[len(AH)-1].Score = shot_num - 1 + AH[len(AH)-1].Penalties
 			AH[len(AH)-1].Res = AH[len(AH)-1].Score - AH[len(AH)-1].Par
 			AH[len(AH)-1].ResName = scoreLabels[AH[len(AH)-1].Res]
 		}
@@ -134,29 +159,34 @@ func GetRoundHoles(rd rnd.Round) AllHoles {
 	return AH
 }
 
+// Rate of missed mandos on each course hole across every round played
+func (a AllHoles) setMandoMissRates() {
+	tries := make(map[string]int)
+	misses := make(map[string]int)
+	for _, h := range a {
+		tries[h.Course+"_"+h.Hole] += h.Mandos
+		misses[h.Course+"_"+h.Hole] += h.MissedMandos
+	}
+
+	for i, h := range a {
+		if n := tries[h.Course+"_"+h.Hole]; n > 0 {
+			a[i].MandoMissRate = float64(misses[h.Course+"_"+h.Hole]) / float64(n)
+		}
+	}
+}
+
 func MakeAllHolesCSV(rnds []rnd.Round) {
 
 	var AH AllHoles
 	for _, rd := range rnds {
 		AH = append(AH, GetRoundHoles(rd)...)
 	}
+	AH.setMandoMissRates()
 
-	homedir, _ := os.UserHomeDir()
-	at_csv := filepath.Join(homedir, ".discgolf", "stats", "all_holes.csv")
-
-	f, err := os.Create(at_csv)
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer f.Close()
-
-	w := csv.NewWriter(f)
-	defer w.Flush()
-
-	w.Write(AH.GetHeader())
-
+	lines := [][]string{AH.GetHeader()}
 	for _, t := range AH {
-		w.Write(t.asStrings())
+		lines = append(lines, t.asStrings())
 	}
+	writeStatsCSV("all_holes.csv", lines)
 
 }
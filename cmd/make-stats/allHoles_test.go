@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+// One hole with a mando, played once missing it twice before getting past
+// and once clean
+func TestMandoMissRate(t *testing.T) {
+	tee := rnd.Loc{33.0, -117.0}
+	pin := rnd.Destination(tee, 0, 100)
+	c := rnd.Course{
+		ID: "test",
+		Holes: []rnd.Hole{{
+			ID:     "1",
+			Tees:   []rnd.Tee{{ID: "reg", Loc: tee}},
+			Pins:   []rnd.Pin{{ID: "A", Loc: pin}},
+			Pars:   []rnd.Par{{Tee: "reg", Pin: "A", Par: 3}},
+			Mandos: []rnd.Mando{{ID: "m"}},
+		}},
+	}
+
+	row := func(l rnd.Loc, disc string, mando string) rnd.RoundRow {
+		pen := 0
+		if mando != "" {
+			pen = 1
+		}
+		return rnd.RoundRow{HoleID: "1", TeeID: "reg", PinID: "A", Lat: l[0], Lon: l[1], Disc: disc, Penalty: pen, Mando: mando}
+	}
+	near := rnd.Destination(tee, 0, 95)
+	rethrown := rnd.Round{ID: "2026-10-18-10-00-00_-_test", CourseID: "test", Course: c, Data: rnd.RoundTable{
+		row(tee, "D", "m"),
+		row(tee, "D", "m"),
+		row(tee, "D", ""),
+		row(near, "P", ""),
+		row(pin, "BASKET", ""),
+	}}
+	clean := rnd.Round{ID: "2026-10-19-10-00-00_-_test", CourseID: "test", Course: c, Data: rnd.RoundTable{
+		row(tee, "D", ""),
+		row(near, "P", ""),
+		row(pin, "BASKET", ""),
+	}}
+
+	AH := append(GetRoundHoles(rethrown), GetRoundHoles(clean)...)
+	AH.setMandoMissRates()
+
+	if AH[0].MissedMandos != 1 || AH[0].Penalties != 2 || AH[0].Score != 6 {
+		t.Errorf("re-thrown hole: %d missed %d penalties score %d, want 1 2 6", AH[0].MissedMandos, AH[0].Penalties, AH[0].Score)
+	}
+	for i, h := range AH {
+		if h.MandoMissRate != 0.5 {
+			t.Errorf("round %d: miss rate %.2f, want 0.50", i, h.MandoMissRate)
+		}
+	}
+}
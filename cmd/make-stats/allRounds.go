@@ -1,24 +1,22 @@
 package main
 
 import (
-	"encoding/csv"
-	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"strconv"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
 
 type RoundRow struct {
-	Date     string
-	Course   string
-	Round    string
-	NumHoles int
-	Par      int
-	Total    int
-	Score    int
+	Date      string
+	Course    string
+	Round     string
+	NumHoles  int
+	Par       int
+	Total     int
+	Score     int
+	Penalties int
+	Layout    string
 }
 type AllRounds []RoundRow
 
@@ -31,6 +29,8 @@ func (t RoundRow) asStrings() []string {
 		strconv.Itoa(t.Par),
 		strconv.Itoa(t.Total),
 		strconv.Itoa(t.Score),
+		strconv.Itoa(t.Penalties),
+		t.Layout,
 	}
 }
 
@@ -43,6 +43,8 @@ func (a AllRounds) GetHeader() []string {
 		"Par",
 		"Total",
 		"Score",
+		"Penalties",
+		"Layout",
 	}
 }
 
@@ -57,7 +59,9 @@ func GetRoundRow(rd rnd.Round) RoundRow {
 	num_holes := 0
 	total_shots := 0
 	total_par := 0
+	total_pen := 0
 	for _, r := range rd.Data {
+		total_pen = total_pen + r.Penalty
 
 		if r.Disc == "BASKET" {
 			h := course.GetHole(r.HoleID)
@@ -69,13 +73,15 @@ func GetRoundRow(rd rnd.Round) RoundRow {
 	}
 
 	return RoundRow{
-		Date:     date,
-		Course:   courseID,
-		Round:    roundID,
-		NumHoles: num_holes,
-		Par:      total_par,
-		Total:    total_shots,
-		Score:    total_shots - total_par,
+		Date:      date,
+		Course:    courseID,
+		Round:     roundID,
+		NumHoles:  num_holes,
+		Par:       total_par,
+		Total:     total_shots + total_pen,
+		Score:     total_shots + total_pen - total_par,
+		Penalties: total_pen,
+		Layout:    rd.LayoutID,
 	}
 }
 
@@ -86,34 +92,17 @@ func MakeAllRoundsCSV(rnds []rnd.Round) {
 		AR = append(AR, GetRoundRow(rd))
 	}
 
-	homedir, _ := os.UserHomeDir()
-	at_csv := filepath.Join(homedir, ".discgolf", "stats", "all_rounds.csv")
-
-	f, err := os.Create(at_csv)
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer f.Close()
-
-	w := csv.NewWriter(f)
-	defer w.Flush()
-
-	w.Write(AR.GetHeader())
-
+	lines := [][]string{AR.GetHeader()}
 	for _, t := range AR {
-		w.Write(t.asStrings())
+		lines = append(lines, t.asStrings())
 	}
+	writeStatsCSV("all_rounds.csv", lines)
 
 }
 
 func ParseAllRoundsCSV() AllRounds {
-	homedir, _ := os.UserHomeDir()
-	arCSV := filepath.Join(homedir, ".discgolf", "stats", "all_rounds.csv")
-
-	f, _ := os.Open(arCSV)
-	defer f.Close()
 	var AR AllRounds
-	r := csv.NewReader(f)
+	r := readStatsCSV("all_rounds.csv")
 	r.Read() // burn header
 	for {
 		line, err := r.Read()
@@ -125,14 +114,24 @@ func ParseAllRoundsCSV() AllRounds {
 		par, _ := strconv.Atoi(line[4])
 		total, _ := strconv.Atoi(line[5])
 		score, _ := strconv.Atoi(line[6])
+		pen := 0
+		if len(line) > 7 {
+			pen, _ = strconv.Atoi(line[7])
+		}
+		layout := ""
+		if len(line) > 8 {
+			layout = line[8]
+		}
 		AR = append(AR, RoundRow{
-			Date:     line[0],
-			Course:   line[1],
-			Round:    line[2],
-			NumHoles: num_holes,
-			Par:      par,
-			Total:    total,
-			Score:    score,
+			Date:      line[0],
+			Course:    line[1],
+			Round:     line[2],
+			NumHoles:  num_holes,
+			Par:       par,
+			Total:     total,
+			Score:     score,
+			Penalties: pen,
+			Layout:    layout,
 		})
 	}
 	return AR
@@ -1,33 +1,32 @@
 package main
 
 import (
-	"encoding/csv"
 	"fmt"
 	"io"
-	"math"
-	"os"
-	"path/filepath"
 	"strconv"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
 
 type ThrowRow struct {
-	Date     string
-	Course   string
-	Round    string
-	Hole     string
-	Shot     int
-	Disc     string
-	Lat1     float64
-	Lon1     float64
-	Lat2     float64
-	Lon2     float64
-	LatPin   float64
-	LonPin   float64
-	Dist     float64
-	DistPin  float64
-	ResThrow string
+	Date      string
+	Course    string
+	Round     string
+	Hole      string
+	Shot      int
+	Disc      string
+	Lat1      float64
+	Lon1      float64
+	Lat2      float64
+	Lon2      float64
+	LatPin    float64
+	LonPin    float64
+	Dist      float64
+	DistPin   float64
+	ResThrow  string
+	Penalty   int
+	Elev      float64
+	PlaysLike float64
 }
 type AllThrows []ThrowRow
 
@@ -48,6 +47,9 @@ func (t ThrowRow) asStrings() []string {
 		fmt.Sprintf("%g", t.Dist),
 		fmt.Sprintf("%g", t.DistPin),
 		t.ResThrow,
+		strconv.Itoa(t.Penalty),
+		fmt.Sprintf("%g", t.Elev),
+		fmt.Sprintf("%g", t.PlaysLike),
 	}
 }
 
@@ -65,9 +67,12 @@ func (a AllThrows) GetHeader() []string {
 		"Lon2",
 		"LatPin",
 		"LonPin",
-		"Dist",
-		"DistPin",
+		rnd.WithUnits("Dist"),
+		rnd.WithUnits("DistPin"),
 		"ResThrow",
+		"Penalty",
+		rnd.WithUnits("Elev"),
+		rnd.WithUnits("PlaysLike"),
 	}
 }
 
@@ -100,9 +105,18 @@ func GetRoundThrows(rd rnd.Round) AllThrows {
 			shot_num++
 		}
 
-		d := math.Round(3.281 * rnd.Dist(r.Loc(), nr.Loc()))
+		// after a missed mando the throw is from where the rule put the disc
+		from := rd.Data.ThrownFrom(i)
 
-		dpin := math.Round(3.281 * rnd.Dist(r.Loc(), p.Loc))
+		d := rnd.InUnits(rnd.Dist(from, nr.Loc()))
+
+		elev := 0.0
+		if from.HasAlt() && nr.Loc().HasAlt() {
+			elev = nr.Alt - from.Alt()
+		}
+		pl := rnd.InUnits(rnd.PlaysLike(rnd.Dist(from, nr.Loc()), elev))
+
+		dpin := rnd.InUnits(rnd.Dist(from, p.Loc))
 
 		res := "THROW"
 		if shot_num == 1 && nr.Disc == "BASKET" {
@@ -114,21 +128,24 @@ func GetRoundThrows(rd rnd.Round) AllThrows {
 		}
 
 		AT = append(AT, ThrowRow{
-			Date:     date,
-			Course:   courseID,
-			Round:    roundID,
-			Hole:     r.HoleID,
-			Shot:     shot_num,
-			Disc:     r.Disc,
-			Lat1:     r.Lat,
-			Lon1:     r.Lon,
-			Lat2:     nr.Lat,
-			Lon2:     nr.Lon,
-			LatPin:   p.Loc[0],
-			LonPin:   p.Loc[1],
-			Dist:     d,
-			DistPin:  dpin,
-			ResThrow: res,
+			Date:      date,
+			Course:    courseID,
+			Round:     roundID,
+			Hole:      r.HoleID,
+			Shot:      shot_num,
+			Disc:      r.Disc,
+			Lat1:      from[0],
+			Lon1:      from[1],
+			Lat2:      nr.Lat,
+			Lon2:      nr.Lon,
+			LatPin:    p.Loc[0],
+			LonPin:    p.Loc[1],
+			Dist:      d,
+			DistPin:   dpin,
+			ResThrow:  res,
+			Penalty:   r.Penalty,
+			Elev:      rnd.InUnits(elev),
+			PlaysLike: pl,
 		})
 	}
 
@@ -142,34 +159,17 @@ func MakeAllThrowsCSV(rnds []rnd.Round) {
 		AT = append(AT, GetRoundThrows(rd)...)
 	}
 
-	homedir, _ := os.UserHomeDir()
-	at_csv := filepath.Join(homedir, ".discgolf", "stats", "all_throws.csv")
-
-	f, err := os.Create(at_csv)
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer f.Close()
-
-	w := csv.NewWriter(f)
-	defer w.Flush()
-
-	w.Write(AT.GetHeader())
-
+	lines := [][]string{AT.GetHeader()}
 	for _, t := range AT {
-		w.Write(t.asStrings())
+		lines = append(lines, t.asStrings())
 	}
+	writeStatsCSV("all_throws.csv", lines)
 
 }
 
 func ParseAllThrowsCSV() AllThrows {
-	homedir, _ := os.UserHomeDir()
-	arCSV := filepath.Join(homedir, ".discgolf", "stats", "all_throws.csv")
-
-	f, _ := os.Open(arCSV)
-	defer f.Close()
 	var AT AllThrows
-	r := csv.NewReader(f)
+	r := readStatsCSV("all_throws.csv")
 	r.Read() // burn header
 	for {
 		line, err := r.Read()
@@ -186,23 +186,36 @@ func ParseAllThrowsCSV() AllThrows {
 		lonp, _ := strconv.ParseFloat(line[11], 64)
 		d, _ := strconv.ParseFloat(line[12], 64)
 		dp, _ := strconv.ParseFloat(line[13], 64)
+		pen := 0
+		if len(line) > 15 {
+			pen, _ = strconv.Atoi(line[15])
+		}
+		elev := 0.0
+		pl := d
+		if len(line) > 17 {
+			elev, _ = strconv.ParseFloat(line[16], 64)
+			pl, _ = strconv.ParseFloat(line[17], 64)
+		}
 
 		AT = append(AT, ThrowRow{
-			Date:     line[0],
-			Course:   line[1],
-			Round:    line[2],
-			Hole:     line[3],
-			Shot:     shot_num,
-			Disc:     line[5],
-			Lat1:     lat1,
-			Lon1:     lon1,
-			Lat2:     lat2,
-			Lon2:     lon2,
-			LatPin:   latp,
-			LonPin:   lonp,
-			Dist:     d,
-			DistPin:  dp,
-			ResThrow: line[14],
+			Date:      line[0],
+			Course:    line[1],
+			Round:     line[2],
+			Hole:      line[3],
+			Shot:      shot_num,
+			Disc:      line[5],
+			Lat1:      lat1,
+			Lon1:      lon1,
+			Lat2:      lat2,
+			Lon2:      lon2,
+			LatPin:    latp,
+			LonPin:    lonp,
+			Dist:      d,
+			DistPin:   dp,
+			ResThrow:  line[14],
+			Penalty:   pen,
+			Elev:      elev,
+			PlaysLike: pl,
 		})
 	}
 	return AT
@@ -1,14 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"os"
-	"path"
-	"path/filepath"
 	"strconv"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
@@ -47,16 +44,36 @@ type DashRound struct {
 	ID     string `json:"id"`
 	Date   string `json:"date"`
 	Course string `json:"course"`
+	Layout string `json:"layout"`
 	Score  string `json:"score"`
 }
 
+// Scores are only comparable between rounds played on the same layout
+type LayoutScores struct {
+	Course string   `json:"course"`
+	Layout string   `json:"layout"`
+	Scores LinePlot `json:"scores"`
+}
+
 type Dash struct {
-	Scores       LinePlot    `json:"scores"`
-	Discs        []Disc      `json:"discs"`
-	Rounds       []DashRound `json:"rounds"`
-	Putts        LinePlot    `json:"putts"`
-	Drives       LinePlot    `json:"drives"`
-	DiscSelction LinePlot    `json:"disc_selection"`
+	Scores       []LayoutScores `json:"scores"`
+	Discs        []Disc         `json:"discs"`
+	Rounds       []DashRound    `json:"rounds"`
+	Putts        LinePlot       `json:"putts"`
+	Drives       LinePlot       `json:"drives"`
+	DiscSelction LinePlot       `json:"disc_selection"`
+	Units        string         `json:"units"`
+}
+
+// Edges of the putting distance buckets, in each unit system. The last two
+// are circle 1 and circle 2.
+var puttBuckets = map[string][4]float64{
+	rnd.FEET:   {10, 20, 33, 66},
+	rnd.METERS: {3, 6, 10, 20},
+}
+
+func puttLabel(lo float64, hi float64) string {
+	return fmt.Sprintf("%g - %g %s", lo, hi, rnd.Units())
 }
 
 type myDisc struct {
@@ -79,11 +96,12 @@ type allDisc struct { // id,brand,mold,type,speed,glide,turn,fade
 }
 
 func parseAllDiscs() []allDisc {
-	adiscs_csv := path.Join(rnd.RootDir(), "..", "data", "discs.csv")
-	f, _ := os.Open(adiscs_csv)
-	defer f.Close()
+	b, err := rnd.DefaultStore().ReadDiscCatalog()
+	if err != nil {
+		fmt.Println("disc catalog: " + err.Error())
+	}
 	var allD []allDisc
-	r := csv.NewReader(f)
+	r := csv.NewReader(bytes.NewReader(b))
 	r.Read() // burn header
 	for {
 		line, err := r.Read()
@@ -110,12 +128,12 @@ func parseAllDiscs() []allDisc {
 }
 
 func parseMyDiscs() []myDisc {
-	homedir, _ := os.UserHomeDir()
-	mydiscsCSV := filepath.Join(homedir, ".discgolf", "discs", "discs.csv")
-	f, _ := os.Open(mydiscsCSV)
-	defer f.Close()
+	b, err := rnd.DefaultStore().ReadDiscs()
+	if err != nil {
+		fmt.Println("discs: " + err.Error())
+	}
 	var myD []myDisc
-	r := csv.NewReader(f)
+	r := csv.NewReader(bytes.NewReader(b))
 	r.Read() // burn header
 	for {
 		line, err := r.Read()
@@ -161,19 +179,47 @@ func getDiscs() []Disc {
 	return D
 }
 
-func getScores() LinePlot {
+func getScores() []LayoutScores {
 	AR := ParseAllRoundsCSV()
 
+	var LS []LayoutScores
+	for _, r := range AR {
+		found := false
+		for _, ls := range LS {
+			found = found || (ls.Course == r.Course && ls.Layout == r.Layout)
+		}
+		if !found {
+			LS = append(LS, LayoutScores{
+				Course: r.Course,
+				Layout: r.Layout,
+				Scores: getLayoutScores(AR, r.Course, r.Layout),
+			})
+		}
+	}
+
+	return LS
+}
+
+func getLayoutScores(AR AllRounds, course string, layout string) LinePlot {
+
 	var L []string
 	var ds []int
 	var DS []LineDataset
 	for _, r := range AR {
+		if r.Course != course || r.Layout != layout {
+			continue
+		}
 		L = append(L, r.Date)
 		ds = append(ds, r.Score)
 	}
 
+	label := "Recent Scores - " + course
+	if layout != "" {
+		label = label + " " + layout
+	}
+
 	DS = append(DS, LineDataset{
-		Label:                "Recent Scores",
+		Label:                label,
 		Data:                 ds,
 		BackgroundColor:      "rgba(60,141,188,0.9)",
 		BorderColor:          "rgba(60,141,188,0.8)",
@@ -196,6 +242,7 @@ func getScores() LinePlot {
 
 func getPutts() LinePlot {
 	AT := ParseAllThrowsCSV()
+	b := puttBuckets[rnd.Units()]
 
 	var L []string
 	var ds_10_tries []int
@@ -224,22 +271,22 @@ func getPutts() LinePlot {
 			i++
 		}
 
-		if r.DistPin < 10 {
+		if r.DistPin < b[0] {
 			ds_10_tries[i]++
 			if r.ResThrow == "MAKE" || r.ResThrow == "ACE" {
 				ds_10_makes[i]++
 			}
-		} else if r.DistPin < 20 {
+		} else if r.DistPin < b[1] {
 			ds_20_tries[i]++
 			if r.ResThrow == "MAKE" || r.ResThrow == "ACE" {
 				ds_20_makes[i]++
 			}
-		} else if r.DistPin < 33 {
+		} else if r.DistPin < b[2] {
 			ds_33_tries[i]++
 			if r.ResThrow == "MAKE" || r.ResThrow == "ACE" {
 				ds_33_makes[i]++
 			}
-		} else if r.DistPin < 66 {
+		} else if r.DistPin < b[3] {
 			ds_66_tries[i]++
 			if r.ResThrow == "MAKE" || r.ResThrow == "ACE" {
 				ds_66_makes[i]++
@@ -249,12 +296,9 @@ func getPutts() LinePlot {
 
 	var DS []LineDataset
 
-	var ds_10 []int
-	for i, _ := range ds_10_tries {
-		ds_10 = append(ds_10, (100*ds_10_makes[i])/ds_10_tries[i])
-	}
+	ds_10 := frac(ds_10_makes, ds_10_tries)
 	DS = append(DS, LineDataset{
-		Label:                "0 - 10 ft",
+		Label:                puttLabel(0, b[0]),
 		Data:                 ds_10,
 		BackgroundColor:      "rgba(167, 36, 193, 1)",
 		BorderColor:          "rgba(167, 36, 193, 1)",
@@ -268,12 +312,9 @@ func getPutts() LinePlot {
 		Fill:                 false,
 	})
 
-	var ds_20 []int
-	for i, _ := range ds_20_makes {
-		ds_20 = append(ds_20, (100*ds_20_makes[i])/ds_20_tries[i])
-	}
+	ds_20 := frac(ds_20_makes, ds_20_tries)
 	DS = append(DS, LineDataset{
-		Label:                "10 - 20 ft",
+		Label:                puttLabel(b[0], b[1]),
 		Data:                 ds_20,
 		BackgroundColor:      "rgba(0, 188, 212, 1)",
 		BorderColor:          "rgba(0, 188, 212, 1)",
@@ -287,12 +328,9 @@ func getPutts() LinePlot {
 		Fill:                 false,
 	})
 
-	var ds_33 []int
-	for i, _ := range ds_33_makes {
-		ds_33 = append(ds_33, (100*ds_33_makes[i])/ds_33_tries[i])
-	}
+	ds_33 := frac(ds_33_makes, ds_33_tries)
 	DS = append(DS, LineDataset{
-		Label:                "20 - 33 ft",
+		Label:                puttLabel(b[1], b[2]),
 		Data:                 ds_33,
 		BackgroundColor:      "rgba(214, 220, 57, 1)",
 		BorderColor:          "rgba(214, 220, 57, 1)",
@@ -306,12 +344,9 @@ func getPutts() LinePlot {
 		Fill:                 false,
 	})
 
-	var ds_66 []int
-	for i, _ := range ds_66_makes {
-		ds_66 = append(ds_66, 100*(ds_66_makes[i])/ds_66_tries[i])
-	}
+	ds_66 := frac(ds_66_makes, ds_66_tries)
 	DS = append(DS, LineDataset{
-		Label:                "33 - 66 ft",
+		Label:                puttLabel(b[2], b[3]),
 		Data:                 ds_66,
 		BackgroundColor:      "rgba(60,141,188,0.9)",
 		BorderColor:          "rgba(60,141,188,0.8)",
@@ -355,7 +390,7 @@ func getDrives() LinePlot {
 
 	var DS []LineDataset
 	DS = append(DS, LineDataset{
-		Label:                "Long Drive",
+		Label:                rnd.WithUnits("Long Drive"),
 		Data:                 ds,
 		BackgroundColor:      "rgba(167, 36, 193, 1)",
 		BorderColor:          "rgba(167, 36, 193, 1)",
@@ -388,6 +423,7 @@ func getRounds() []DashRound {
 			ID:     r.Round,
 			Date:   r.Date,
 			Course: r.Course,
+			Layout: r.Layout,
 			Score:  score,
 		})
 	}
@@ -412,9 +448,14 @@ func plus(a []int, b []int) []int {
 	return c
 }
 
+// Percentages of a in b, 0 where b is empty
 func frac(a []int, b []int) []int {
 	var c []int
 	for i := range a {
+		if b[i] == 0 {
+			c = append(c, 0)
+			continue
+		}
 		c = append(c, (100*a[i])/b[i])
 	}
 	return c
@@ -495,9 +536,6 @@ func getDiscSelection() LinePlot {
 }
 
 func MakeDash() {
-	homedir, _ := os.UserHomeDir()
-	dashFile := filepath.Join(homedir, ".discgolf", "stats", "dash.json")
-
 	D := Dash{
 		Scores:       getScores(),
 		Discs:        getDiscs(),
@@ -505,9 +543,12 @@ func MakeDash() {
 		Putts:        getPutts(),
 		Drives:       getDrives(),
 		DiscSelction: getDiscSelection(),
+		Units:        rnd.Units(),
 	}
 
 	file, _ := json.MarshalIndent(D, "", "	")
-	_ = ioutil.WriteFile(dashFile, file, 0644)
+	if err := rnd.DefaultStore().WriteStats("dash.json", file); err != nil {
+		fmt.Println(err)
+	}
 
 }
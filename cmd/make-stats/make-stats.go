@@ -1,40 +1,56 @@
 package main
 
 import (
-	"io/ioutil"
-	"log"
-	"os"
-	"path/filepath"
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
 
 	"github.com/jacobwood27/go-dg-record/internal/rnd"
 )
 
-func rndFiles() []string {
-	homedir, _ := os.UserHomeDir()
-	rnddir := filepath.Join(homedir, ".discgolf", "rounds")
+func writeStatsCSV(name string, lines [][]string) {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.WriteAll(lines)
 
-	files, err := ioutil.ReadDir(rnddir)
+	if err := rnd.DefaultStore().WriteStats(name, b.Bytes()); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func readStatsCSV(name string) *csv.Reader {
+	b, err := rnd.DefaultStore().ReadStats(name)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Println(name + ": " + err.Error())
 	}
+	return csv.NewReader(bytes.NewReader(b))
+}
 
-	var S []string
-	for _, f := range files {
-		S = append(S, filepath.Join(rnddir, f.Name(), f.Name()+".csv"))
+// Saves the scoring history of every course that has been played
+func MakeCourseStats(rnds []rnd.Round) {
+	var ids []string
+	for _, rd := range rnds {
+		if !contains(ids, rd.CourseID) {
+			ids = append(ids, rd.CourseID)
+		}
 	}
 
-	return S
+	for _, id := range ids {
+		rnd.GetCourseStats(rnd.GetCourse(id), rnds).Save()
+	}
 }
 
 func main() {
+	flag.Parse()
 
-	rfiles := rndFiles()
 	var rnds []rnd.Round
-	for _, rfile := range rfiles {
-		rd := rnd.ReadRoundCSV(rfile)
+	for _, id := range rnd.GetRoundIDs() {
+		rd := rnd.LoadRound(id)
 		rnds = append(rnds, rd)
 	}
 
+	MakeCourseStats(rnds)
 	MakeAllThrowsCSV(rnds)
 	MakeAllHolesCSV(rnds)
 	MakeAllRoundsCSV(rnds)
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+func main() {
+	id := flag.String("id", "", "ID to save the merged course as (default ours)")
+	dryRun := flag.Bool("dry-run", false, "print the differences and conflicts without saving")
+	force := flag.Bool("force", false, "replace a course already in the store, keeping the old layout as a revision")
+
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Println("usage: merge-course [-home dir] [-id mergedID] [-dry-run] [-force] <ours> <theirs>")
+		os.Exit(2)
+	}
+	ours := rnd.LoadCourse(flag.Arg(0))
+	theirs := rnd.LoadCourse(flag.Arg(1))
+
+	changes := rnd.DiffCourses(ours, theirs)
+	for _, cc := range changes {
+		fmt.Println(cc)
+	}
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+	}
+
+	m := rnd.MergeCourses(ours, theirs)
+	if n := len(m.Conflicts) - len(ours.Conflicts); n > 0 {
+		fmt.Printf("\n%d conflicts, resolve them in edit-course:\n", n)
+		for _, mc := range m.Conflicts[len(ours.Conflicts):] {
+			fmt.Println(mc)
+		}
+	}
+
+	if *dryRun {
+		return
+	}
+	if *id != "" {
+		m.ID = *id
+	}
+	if rnd.CourseExists(m.ID) && !*force {
+		log.Fatal(m.ID + " is already in the store, use -force to replace it or -id to save it as another course")
+	}
+	m.ReplaceCourse()
+	fmt.Println("\nSaved", m.ID)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jacobwood27/go-dg-record/internal/rnd"
+)
+
+func main() {
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: validate-course [-home dir] [<courseID|course.json> ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// every course in the store unless some are named
+	ids := flag.Args()
+	if len(ids) == 0 {
+		ids = rnd.GetCourseIDs()
+	}
+
+	n := 0
+	for _, id := range ids {
+		crs := rnd.LoadCourse(id)
+		for _, w := range crs.Validate() {
+			fmt.Println(id + ": " + w)
+			n++
+		}
+	}
+
+	if n > 0 {
+		fmt.Printf("%d problems found\n", n)
+		os.Exit(1)
+	}
+}
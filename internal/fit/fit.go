@@ -0,0 +1,413 @@
+// Package fit decodes the parts of Garmin FIT activity files a round needs:
+// record messages with time, position and altitude, and the laps and marker
+// events a lap button press leaves.
+package fit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Global message numbers
+const (
+	mesgLap    = 19
+	mesgRecord = 20
+	mesgEvent  = 21
+)
+
+// Field numbers, the timestamp is the same in every message
+const (
+	fieldTimestamp = 253
+
+	recordLat              = 0
+	recordLon              = 1
+	recordAltitude         = 2
+	recordGPSAccuracy      = 31
+	recordEnhancedAltitude = 78
+
+	eventEvent     = 0
+	eventEventType = 1
+
+	lapTrigger = 24
+)
+
+// Event and event_type values
+const (
+	eventLap         = 9
+	eventTypeMarker  = 3
+	lapTriggerManual = 0
+)
+
+// FIT times count seconds from 1989-12-31 UTC
+var epoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var (
+	ErrNotFIT = errors.New("not a FIT file")
+	ErrCRC    = errors.New("FIT file CRC mismatch")
+)
+
+// A point of the recorded track
+type Record struct {
+	Time   time.Time
+	Lat    float64
+	Lon    float64
+	Alt    float64
+	HasPos bool
+	HasAlt bool
+
+	// horizontal error in meters, 0 if the watch didn't say
+	Accuracy float64
+}
+
+// A press of the lap button, or a marker event
+type Marker struct {
+	Time time.Time
+	Kind string // "lap" or "marker"
+}
+
+type Activity struct {
+	Records []Record
+	Markers []Marker
+}
+
+type fieldDef struct {
+	num      byte
+	size     byte
+	baseType byte
+}
+
+type msgDef struct {
+	global   uint16
+	order    binary.ByteOrder
+	fields   []fieldDef
+	devBytes int
+}
+
+type decoder struct {
+	r    *bufio.Reader
+	crc  uint16
+	left uint32 // data bytes left in the current file
+
+	defs   [16]*msgDef
+	lastTS uint32
+	act    Activity
+}
+
+func (d *decoder) read(b []byte) error {
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	d.crc = crc16(d.crc, b)
+	return nil
+}
+
+// Reads from the data section, which mustn't run past its stated size
+func (d *decoder) readData(b []byte) error {
+	if uint32(len(b)) > d.left {
+		return fmt.Errorf("FIT message runs past the end of the data")
+	}
+	d.left -= uint32(len(b))
+	return d.read(b)
+}
+
+// Decode reads every FIT file chained in r
+func Decode(r io.Reader) (Activity, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+
+	for n := 0; ; n++ {
+		if _, err := d.r.Peek(1); err == io.EOF && n > 0 {
+			break
+		}
+		if err := d.file(); err != nil {
+			return d.act, err
+		}
+	}
+
+	sort.SliceStable(d.act.Markers, func(i, j int) bool {
+		return d.act.Markers[i].Time.Before(d.act.Markers[j].Time)
+	})
+	return d.act, nil
+}
+
+func ReadFile(name string) (Activity, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Activity{}, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+func (d *decoder) file() error {
+	d.crc = 0
+	size := make([]byte, 1)
+	if err := d.read(size); err != nil {
+		return err
+	}
+	if size[0] < 12 {
+		return ErrNotFIT
+	}
+	hdr := make([]byte, size[0]-1)
+	if err := d.read(hdr); err != nil {
+		return err
+	}
+	if string(hdr[7:11]) != ".FIT" {
+		return ErrNotFIT
+	}
+	d.left = binary.LittleEndian.Uint32(hdr[3:7])
+
+	// a header CRC of 0 means it wasn't computed
+	if size[0] >= 14 {
+		if c := binary.LittleEndian.Uint16(hdr[11:13]); c != 0 && c != crc16(0, append(size, hdr[:11]...)) {
+			return ErrCRC
+		}
+	}
+
+	d.defs = [16]*msgDef{}
+	for d.left > 0 {
+		if err := d.message(); err != nil {
+			return err
+		}
+	}
+
+	want := d.crc
+	crc := make([]byte, 2)
+	if err := d.read(crc); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint16(crc) != want {
+		return ErrCRC
+	}
+	return nil
+}
+
+func (d *decoder) message() error {
+	h := make([]byte, 1)
+	if err := d.readData(h); err != nil {
+		return err
+	}
+
+	// compressed timestamp header, a 5 bit offset from the last timestamp
+	if h[0]&0x80 != 0 {
+		local := (h[0] >> 5) & 0x3
+		off := uint32(h[0] & 0x1f)
+		ts := d.lastTS + ((off - d.lastTS) & 0x1f)
+		d.lastTS = ts
+		return d.data(local, &ts)
+	}
+
+	local := h[0] & 0x0f
+	if h[0]&0x40 != 0 {
+		return d.definition(local, h[0]&0x20 != 0)
+	}
+	return d.data(local, nil)
+}
+
+func (d *decoder) definition(local byte, dev bool) error {
+	b := make([]byte, 5)
+	if err := d.readData(b); err != nil {
+		return err
+	}
+
+	def := &msgDef{order: binary.LittleEndian}
+	if b[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(b[2:4])
+
+	fb := make([]byte, 3*int(b[4]))
+	if err := d.readData(fb); err != nil {
+		return err
+	}
+	for i := 0; i < len(fb); i += 3 {
+		def.fields = append(def.fields, fieldDef{num: fb[i], size: fb[i+1], baseType: fb[i+2]})
+	}
+
+	// developer fields are only ever skipped
+	if dev {
+		n := make([]byte, 1)
+		if err := d.readData(n); err != nil {
+			return err
+		}
+		db := make([]byte, 3*int(n[0]))
+		if err := d.readData(db); err != nil {
+			return err
+		}
+		for i := 0; i < len(db); i += 3 {
+			def.devBytes += int(db[i+1])
+		}
+	}
+
+	d.defs[local] = def
+	return nil
+}
+
+func (d *decoder) data(local byte, ts *uint32) error {
+	def := d.defs[local]
+	if def == nil {
+		return fmt.Errorf("FIT data message for undefined local type %d", local)
+	}
+
+	vals := map[byte]int64{}
+	for _, f := range def.fields {
+		b := make([]byte, f.size)
+		if err := d.readData(b); err != nil {
+			return err
+		}
+		if v, ok := value(b, f.baseType, def.order); ok {
+			vals[f.num] = v
+		}
+	}
+	if def.devBytes > 0 {
+		if err := d.readData(make([]byte, def.devBytes)); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := vals[fieldTimestamp]; ok {
+		d.lastTS = uint32(v)
+	} else if ts != nil {
+		vals[fieldTimestamp] = int64(*ts)
+	}
+
+	t, hasTime := vals[fieldTimestamp]
+	if !hasTime {
+		return nil
+	}
+	when := epoch.Add(time.Duration(t) * time.Second)
+
+	switch def.global {
+	case mesgRecord:
+		rec := Record{Time: when}
+		lat, okLat := vals[recordLat]
+		lon, okLon := vals[recordLon]
+		if okLat && okLon {
+			rec.Lat = semicircles(lat)
+			rec.Lon = semicircles(lon)
+			rec.HasPos = true
+		}
+		alt, okAlt := vals[recordEnhancedAltitude]
+		if !okAlt {
+			alt, okAlt = vals[recordAltitude]
+		}
+		if okAlt {
+			rec.Alt = float64(alt)/5 - 500
+			rec.HasAlt = true
+		}
+		if acc, ok := vals[recordGPSAccuracy]; ok {
+			rec.Accuracy = float64(acc)
+		}
+		d.act.Records = append(d.act.Records, rec)
+
+	case mesgLap:
+		// only laps the button ended, not ones by distance or time
+		if trig, ok := vals[lapTrigger]; !ok || trig == lapTriggerManual {
+			d.act.Markers = append(d.act.Markers, Marker{Time: when, Kind: "lap"})
+		}
+
+	case mesgEvent:
+		if vals[eventEventType] == eventTypeMarker {
+			d.act.Markers = append(d.act.Markers, Marker{Time: when, Kind: "marker"})
+		}
+	}
+	return nil
+}
+
+func semicircles(v int64) float64 {
+	return float64(v) * 180 / math.Pow(2, 31)
+}
+
+// The integer value of a field, false for the invalid value of its type and
+// for types that aren't integers
+func value(b []byte, baseType byte, order binary.ByteOrder) (int64, bool) {
+	signed := false
+	switch baseType & 0x1f {
+	case 0x01, 0x03, 0x05, 0x0e:
+		signed = true
+	case 0x00, 0x02, 0x04, 0x06, 0x0a, 0x0b, 0x0c, 0x0d, 0x0f, 0x10:
+	default:
+		return 0, false
+	}
+
+	var u uint64
+	var invalid uint64
+	switch len(b) {
+	case 1:
+		u = uint64(b[0])
+		invalid = 0xff
+		if signed {
+			invalid = 0x7f
+		}
+	case 2:
+		u = uint64(order.Uint16(b))
+		invalid = 0xffff
+		if signed {
+			invalid = 0x7fff
+		}
+	case 4:
+		u = uint64(order.Uint32(b))
+		invalid = 0xffffffff
+		if signed {
+			invalid = 0x7fffffff
+		}
+	case 8:
+		u = order.Uint64(b)
+		invalid = math.MaxUint64
+		if signed {
+			invalid = math.MaxInt64
+		}
+	default:
+		return 0, false // arrays
+	}
+
+	// the z types use 0 for invalid
+	switch baseType & 0x1f {
+	case 0x0a, 0x0b, 0x0c, 0x10:
+		invalid = 0
+	}
+	if u == invalid {
+		return 0, false
+	}
+
+	if signed {
+		switch len(b) {
+		case 1:
+			return int64(int8(u)), true
+		case 2:
+			return int64(int16(u)), true
+		case 4:
+			return int64(int32(u)), true
+		}
+		return int64(u), true
+	}
+	return int64(u), true
+}
+
+var crcTable = [16]uint16{
+	0x0000, 0xcc01, 0xd801, 0x1400, 0xf001, 0x3c00, 0x2800, 0xe401,
+	0xa001, 0x6c00, 0x7800, 0xb401, 0x5000, 0x9c01, 0x8801, 0x4400,
+}
+
+// The FIT CRC-16 of b continuing from crc
+func crc16(crc uint16, b []byte) uint16 {
+	for _, c := range b {
+		tmp := crcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ crcTable[c&0xf]
+
+		tmp = crcTable[crc&0xf]
+		crc = (crc >> 4) & 0x0fff
+		crc = crc ^ tmp ^ crcTable[(c>>4)&0xf]
+	}
+	return crc
+}
@@ -0,0 +1,202 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+// Builds the data section of a FIT file a message at a time
+type builder struct {
+	data []byte
+}
+
+// A definition message for local type local, fields as number, size, base
+// type triples
+func (b *builder) define(local byte, global uint16, order binary.ByteOrder, fields [][3]byte, dev [][3]byte) {
+	h := 0x40 | local
+	if dev != nil {
+		h |= 0x20
+	}
+	arch := byte(0)
+	if order == binary.BigEndian {
+		arch = 1
+	}
+	b.data = append(b.data, h, 0, arch)
+	b.data = append(b.data, u16(order, global)...)
+	b.data = append(b.data, byte(len(fields)))
+	for _, f := range fields {
+		b.data = append(b.data, f[:]...)
+	}
+	if dev != nil {
+		b.data = append(b.data, byte(len(dev)))
+		for _, f := range dev {
+			b.data = append(b.data, f[:]...)
+		}
+	}
+}
+
+// A data message with header h and its field bytes
+func (b *builder) message(h byte, fields ...[]byte) {
+	b.data = append(b.data, h)
+	for _, f := range fields {
+		b.data = append(b.data, f...)
+	}
+}
+
+// The data wrapped in a 14 byte header and followed by its CRC
+func (b *builder) file() []byte {
+	hdr := []byte{14, 0x20, 0, 0}
+	hdr = append(hdr, u32(binary.LittleEndian, uint32(len(b.data)))...)
+	hdr = append(hdr, ".FIT"...)
+	hdr = append(hdr, u16(binary.LittleEndian, crc16(0, hdr))...)
+
+	f := append(hdr, b.data...)
+	return append(f, u16(binary.LittleEndian, crc16(0, f))...)
+}
+
+func u8(v byte) []byte { return []byte{v} }
+
+func u16(order binary.ByteOrder, v uint16) []byte {
+	b := make([]byte, 2)
+	order.PutUint16(b, v)
+	return b
+}
+
+func u32(order binary.ByteOrder, v uint32) []byte {
+	b := make([]byte, 4)
+	order.PutUint32(b, v)
+	return b
+}
+
+func semi(deg float64) []byte {
+	return u32(binary.LittleEndian, uint32(int32(math.Round(deg*math.Pow(2, 31)/180))))
+}
+
+func fitTime(t time.Time) uint32 {
+	return uint32(t.Sub(epoch).Seconds())
+}
+
+var start = time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)
+
+// Two records in full, one with a compressed timestamp and one without a
+// position, a manual lap and one ended by distance, and a marker event with
+// a developer field to skip
+func testActivity() []byte {
+	le, be := binary.LittleEndian, binary.BigEndian
+	var b builder
+
+	b.define(0, mesgRecord, le, [][3]byte{
+		{fieldTimestamp, 4, 0x86},
+		{recordLat, 4, 0x85},
+		{recordLon, 4, 0x85},
+		{recordEnhancedAltitude, 4, 0x86},
+		{recordGPSAccuracy, 1, 0x02},
+	}, nil)
+	t0 := fitTime(start)
+	b.message(0, u32(le, t0), semi(33.079323), semi(-117.058426), u32(le, (120+500)*5), u8(4))
+	b.message(0, u32(le, t0+5), semi(33.079400), semi(-117.058500), u32(le, (121+500)*5), u8(0xff))
+	// compressed header, local type 3 has no timestamp field, 3 s on
+	b.define(3, mesgRecord, le, [][3]byte{
+		{recordLat, 4, 0x85},
+		{recordLon, 4, 0x85},
+		{recordEnhancedAltitude, 4, 0x86},
+		{recordGPSAccuracy, 1, 0x02},
+	}, nil)
+	b.message(0x80|3<<5|byte((t0+8)&0x1f), semi(33.079500), semi(-117.058600), u32(le, (122+500)*5), u8(6))
+	b.message(0, u32(le, t0+10), u32(le, 0x7fffffff), u32(le, 0x7fffffff), u32(le, 0xffffffff), u8(0xff))
+
+	b.define(1, mesgLap, be, [][3]byte{
+		{fieldTimestamp, 4, 0x86},
+		{lapTrigger, 1, 0x00},
+	}, nil)
+	b.message(1, u32(be, t0+20), u8(lapTriggerManual))
+	b.message(1, u32(be, t0+30), u8(3))
+
+	b.define(2, mesgEvent, le, [][3]byte{
+		{fieldTimestamp, 4, 0x86},
+		{eventEvent, 1, 0x00},
+		{eventEventType, 1, 0x00},
+	}, [][3]byte{{0, 2, 0}})
+	b.message(2, u32(le, t0+15), u8(eventLap), u8(eventTypeMarker), u16(le, 0xbeef))
+
+	return b.file()
+}
+
+func TestDecode(t *testing.T) {
+	act, err := Decode(bytes.NewReader(testActivity()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Record{
+		{Time: start, Lat: 33.079323, Lon: -117.058426, Alt: 120, HasPos: true, HasAlt: true, Accuracy: 4},
+		{Time: start.Add(5 * time.Second), Lat: 33.079400, Lon: -117.058500, Alt: 121, HasPos: true, HasAlt: true},
+		{Time: start.Add(8 * time.Second), Lat: 33.079500, Lon: -117.058600, Alt: 122, HasPos: true, HasAlt: true, Accuracy: 6},
+		{Time: start.Add(10 * time.Second)},
+	}
+	if len(act.Records) != len(want) {
+		t.Fatalf("%d records, want %d", len(act.Records), len(want))
+	}
+	for i, w := range want {
+		r := act.Records[i]
+		if !r.Time.Equal(w.Time) || r.HasPos != w.HasPos || r.HasAlt != w.HasAlt || r.Accuracy != w.Accuracy ||
+			math.Abs(r.Lat-w.Lat) > 1e-6 || math.Abs(r.Lon-w.Lon) > 1e-6 || math.Abs(r.Alt-w.Alt) > 1e-6 {
+			t.Errorf("record %d: %+v, want %+v", i, r, w)
+		}
+	}
+
+	wantMarkers := []Marker{
+		{Time: start.Add(15 * time.Second), Kind: "marker"},
+		{Time: start.Add(20 * time.Second), Kind: "lap"},
+	}
+	if len(act.Markers) != len(wantMarkers) {
+		t.Fatalf("markers %v, want %v", act.Markers, wantMarkers)
+	}
+	for i, w := range wantMarkers {
+		if !act.Markers[i].Time.Equal(w.Time) || act.Markers[i].Kind != w.Kind {
+			t.Errorf("marker %d: %v, want %v", i, act.Markers[i], w)
+		}
+	}
+}
+
+func TestDecodeChained(t *testing.T) {
+	f := testActivity()
+	act, err := Decode(bytes.NewReader(append(append([]byte{}, f...), f...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(act.Records) != 8 || len(act.Markers) != 4 {
+		t.Errorf("%d records and %d markers from two files, want 8 and 4", len(act.Records), len(act.Markers))
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	f := testActivity()
+
+	corrupt := append([]byte{}, f...)
+	corrupt[20] ^= 0xff
+
+	badHeader := append([]byte{}, f...)
+	badHeader[9] = 'X'
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"corrupt", corrupt, ErrCRC},
+		{"not fit", badHeader, ErrNotFIT},
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"truncated", f[:len(f)-10], io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		if _, err := Decode(bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package geo does distances, bearings and projections on the WGS84
+// ellipsoid. Angles are in degrees and distances in meters.
+package geo
+
+import "math"
+
+// WGS84 ellipsoid
+const (
+	A = 6378137.0         // semi-major axis
+	F = 1 / 298.257223563 // flattening
+	B = A * (1 - F)       // semi-minor axis
+)
+
+// Mean earth radius, used when Vincenty's method fails to converge
+const R = 6371008.8
+
+const (
+	maxIter = 200
+	eps     = 1e-12
+)
+
+func rad(d float64) float64 {
+	return d * math.Pi / 180.0
+}
+
+func deg(r float64) float64 {
+	return r * 180.0 / math.Pi
+}
+
+// Wraps an angle into [0, 360)
+func wrap360(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// Wraps a longitude into [-180, 180)
+func wrap180(d float64) float64 {
+	return wrap360(d+180) - 180
+}
+
+// Great circle distance on a sphere of radius R
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1, φ2 := rad(lat1), rad(lat2)
+	Δφ := φ2 - φ1
+	Δλ := rad(lon2 - lon1)
+
+	a := math.Sin(Δφ/2)*math.Sin(Δφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(Δλ/2)*math.Sin(Δλ/2)
+	return 2 * R * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Inverse solves the geodesic between two points by Vincenty's method,
+// returning the distance and the forward azimuths at both ends. For nearly
+// antipodal points, where the method doesn't converge, it falls back to the
+// spherical distance and azimuths.
+func Inverse(lat1, lon1, lat2, lon2 float64) (dist float64, azi1 float64, azi2 float64) {
+	if lat1 == lat2 && lon1 == lon2 {
+		return 0, 0, 0
+	}
+
+	L := rad(wrap180(lon2 - lon1))
+	U1 := math.Atan((1 - F) * math.Tan(rad(lat1)))
+	U2 := math.Atan((1 - F) * math.Tan(rad(lat2)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	λ := L
+	var sinλ, cosλ, sinσ, cosσ, σ, cos2α, cos2σm float64
+	converged := false
+	for i := 0; i < maxIter; i++ {
+		sinλ, cosλ = math.Sincos(λ)
+		sinσ = math.Hypot(cosU2*sinλ, cosU1*sinU2-sinU1*cosU2*cosλ)
+		if sinσ == 0 {
+			return 0, 0, 0
+		}
+		cosσ = sinU1*sinU2 + cosU1*cosU2*cosλ
+		σ = math.Atan2(sinσ, cosσ)
+		sinα := cosU1 * cosU2 * sinλ / sinσ
+		cos2α = 1 - sinα*sinα
+
+		// both points on the equator
+		cos2σm = 0
+		if cos2α != 0 {
+			cos2σm = cosσ - 2*sinU1*sinU2/cos2α
+		}
+
+		C := F / 16 * cos2α * (4 + F*(4-3*cos2α))
+		λp := λ
+		λ = L + (1-C)*F*sinα*(σ+C*sinσ*(cos2σm+C*cosσ*(-1+2*cos2σm*cos2σm)))
+		if math.Abs(λ-λp) < eps {
+			converged = true
+			break
+		}
+	}
+
+	if !converged {
+		return haversine(lat1, lon1, lat2, lon2), sphericalBearing(lat1, lon1, lat2, lon2), wrap360(sphericalBearing(lat2, lon2, lat1, lon1) + 180)
+	}
+
+	u2 := cos2α * (A*A - B*B) / (B * B)
+	bigA := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	bigB := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+	Δσ := bigB * sinσ * (cos2σm + bigB/4*(cosσ*(-1+2*cos2σm*cos2σm)-
+		bigB/6*cos2σm*(-3+4*sinσ*sinσ)*(-3+4*cos2σm*cos2σm)))
+
+	dist = B * bigA * (σ - Δσ)
+	azi1 = deg(math.Atan2(cosU2*sinλ, cosU1*sinU2-sinU1*cosU2*cosλ))
+	azi2 = deg(math.Atan2(cosU1*sinλ, -sinU1*cosU2+cosU1*sinU2*cosλ))
+
+	return dist, wrap360(azi1), wrap360(azi2)
+}
+
+func sphericalBearing(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1, φ2 := rad(lat1), rad(lat2)
+	Δλ := rad(lon2 - lon1)
+	y := math.Sin(Δλ) * math.Cos(φ2)
+	x := math.Cos(φ1)*math.Sin(φ2) - math.Sin(φ1)*math.Cos(φ2)*math.Cos(Δλ)
+	return wrap360(deg(math.Atan2(y, x)))
+}
+
+// Distance in meters along the ellipsoid
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	d, _, _ := Inverse(lat1, lon1, lat2, lon2)
+	return d
+}
+
+// Initial bearing from the first point to the second, clockwise from north
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	_, azi1, _ := Inverse(lat1, lon1, lat2, lon2)
+	return azi1
+}
+
+// Destination solves the direct problem by Vincenty's method, giving the
+// point dist meters from lat, lon along the initial bearing brg
+func Destination(lat, lon, brg, dist float64) (float64, float64) {
+	sinα1, cosα1 := math.Sincos(rad(brg))
+	tanU1 := (1 - F) * math.Tan(rad(lat))
+	cosU1 := 1 / math.Sqrt(1+tanU1*tanU1)
+	sinU1 := tanU1 * cosU1
+
+	σ1 := math.Atan2(tanU1, cosα1)
+	sinα := cosU1 * sinα1
+	cos2α := 1 - sinα*sinα
+
+	u2 := cos2α * (A*A - B*B) / (B * B)
+	bigA := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	bigB := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+
+	σ := dist / (B * bigA)
+	var sinσ, cosσ, cos2σm float64
+	for i := 0; i < maxIter; i++ {
+		cos2σm = math.Cos(2*σ1 + σ)
+		sinσ, cosσ = math.Sincos(σ)
+		Δσ := bigB * sinσ * (cos2σm + bigB/4*(cosσ*(-1+2*cos2σm*cos2σm)-
+			bigB/6*cos2σm*(-3+4*sinσ*sinσ)*(-3+4*cos2σm*cos2σm)))
+		σp := σ
+		σ = dist/(B*bigA) + Δσ
+		if math.Abs(σ-σp) < eps {
+			break
+		}
+	}
+	cos2σm = math.Cos(2*σ1 + σ)
+	sinσ, cosσ = math.Sincos(σ)
+
+	x := sinU1*sinσ - cosU1*cosσ*cosα1
+	φ2 := math.Atan2(sinU1*cosσ+cosU1*sinσ*cosα1, (1-F)*math.Hypot(sinα, x))
+	λ := math.Atan2(sinσ*sinα1, cosU1*cosσ-sinU1*sinσ*cosα1)
+	C := F / 16 * cos2α * (4 + F*(4-3*cos2α))
+	L := λ - (1-C)*F*sinα*(σ+C*sinσ*(cos2σm+C*cosσ*(-1+2*cos2σm*cos2σm)))
+
+	return deg(φ2), wrap180(lon + deg(L))
+}
+
+// Projection is a local east/north plane tangent to the ellipsoid at an
+// origin. It is good to a few centimeters across a course.
+type Projection struct {
+	Lat0 float64
+	Lon0 float64
+
+	// meters per degree of longitude and latitude at the origin
+	kx float64
+	ky float64
+}
+
+func NewProjection(lat0, lon0 float64) Projection {
+	e2 := F * (2 - F)
+	s := math.Sin(rad(lat0))
+	w := 1 - e2*s*s
+
+	// prime vertical and meridional radii of curvature
+	N := A / math.Sqrt(w)
+	M := A * (1 - e2) / (w * math.Sqrt(w))
+
+	return Projection{
+		Lat0: lat0,
+		Lon0: lon0,
+		kx:   rad(N * math.Cos(rad(lat0))),
+		ky:   rad(M),
+	}
+}
+
+// East and north offsets in meters of lat, lon from the origin
+func (p Projection) Forward(lat, lon float64) (float64, float64) {
+	return wrap180(lon-p.Lon0) * p.kx, (lat - p.Lat0) * p.ky
+}
+
+// The lat, lon of a point x meters east and y meters north of the origin
+func (p Projection) Inverse(x, y float64) (float64, float64) {
+	return p.Lat0 + y/p.ky, wrap180(p.Lon0 + x/p.kx)
+}
@@ -0,0 +1,121 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func dms(d, m, s float64) float64 {
+	if d < 0 {
+		return d - m/60 - s/3600
+	}
+	return d + m/60 + s/3600
+}
+
+// Vincenty's own worked example, Flinders Peak to Buninyong (Geoscience
+// Australia)
+var (
+	flindersLat = dms(-37, 57, 3.72030)
+	flindersLon = dms(144, 25, 29.52440)
+	buninyLat   = dms(-37, 39, 10.15610)
+	buninyLon   = dms(143, 55, 35.38390)
+	flindersAzi = dms(306, 52, 5.37)
+	buninyAzi   = dms(127, 10, 25.07) + 180 // given as the reverse azimuth
+	flindersS   = 54972.271
+)
+
+func TestInverse(t *testing.T) {
+	cases := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		dist                   float64
+	}{
+		{"flinders peak to buninyong", flindersLat, flindersLon, buninyLat, buninyLon, flindersS},
+		{"one degree along the equator", 0, 0, 0, 1, 111319.491},
+		{"one degree of meridian from the equator", 0, 0, 1, 0, 110574.389},
+		{"meridian quadrant", 0, 0, 90, 0, 10001965.729},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111319.491},
+		{"same point", 33.08, -117.06, 33.08, -117.06, 0},
+	}
+
+	for _, c := range cases {
+		d := Distance(c.lat1, c.lon1, c.lat2, c.lon2)
+		if math.Abs(d-c.dist) > 1e-3 {
+			t.Errorf("%s: got %.4f m, want %.3f m", c.name, d, c.dist)
+		}
+	}
+
+	_, azi1, azi2 := Inverse(flindersLat, flindersLon, buninyLat, buninyLon)
+	if math.Abs(azi1-flindersAzi) > 0.01/3600 {
+		t.Errorf("initial azimuth %.6f, want %.6f", azi1, flindersAzi)
+	}
+	if math.Abs(azi2-wrap360(buninyAzi)) > 0.01/3600 {
+		t.Errorf("final azimuth %.6f, want %.6f", azi2, wrap360(buninyAzi))
+	}
+}
+
+func TestAntipodal(t *testing.T) {
+	d := Distance(0, 0, 0.5, 179.7)
+	if math.IsNaN(d) || d < 19e6 || d > 20.1e6 {
+		t.Errorf("nearly antipodal distance %f", d)
+	}
+}
+
+func TestBearing(t *testing.T) {
+	cases := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		brg                    float64
+	}{
+		{"north", 10, 10, 11, 10, 0},
+		{"east on the equator", 0, 10, 0, 11, 90},
+		{"south", 10, 10, 9, 10, 180},
+		{"west on the equator", 0, 10, 0, 9, 270},
+		{"flinders peak to buninyong", flindersLat, flindersLon, buninyLat, buninyLon, flindersAzi},
+	}
+
+	for _, c := range cases {
+		b := Bearing(c.lat1, c.lon1, c.lat2, c.lon2)
+		if math.Abs(b-c.brg) > 1e-5 {
+			t.Errorf("%s: got %.6f, want %.6f", c.name, b, c.brg)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	lat, lon := Destination(flindersLat, flindersLon, flindersAzi, flindersS)
+	if math.Abs(lat-buninyLat) > 1e-4/3600 || math.Abs(lon-buninyLon) > 1e-4/3600 {
+		t.Errorf("got %.8f, %.8f, want %.8f, %.8f", lat, lon, buninyLat, buninyLon)
+	}
+
+	// there and back again, to within the 0.1 mm Vincenty claims
+	for _, brg := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+		lat, lon := Destination(33.079323, -117.058426, brg, 250)
+		d, azi, _ := Inverse(33.079323, -117.058426, lat, lon)
+		if math.Abs(d-250) > 1e-4 || math.Abs(wrap180(azi-brg)) > 1e-6 {
+			t.Errorf("bearing %v: came back %f m at %f", brg, d, azi)
+		}
+	}
+}
+
+func TestProjection(t *testing.T) {
+	lat0, lon0 := 45.0, -117.0
+	p := NewProjection(lat0, lon0)
+
+	for _, brg := range []float64{0, 30, 60, 120, 200, 300} {
+		lat, lon := Destination(lat0, lon0, brg, 300)
+		x, y := p.Forward(lat, lon)
+
+		if d := math.Hypot(x, y); math.Abs(d-300) > 0.01 {
+			t.Errorf("bearing %v: projected distance %f", brg, d)
+		}
+		if b := wrap360(deg(math.Atan2(x, y))); math.Abs(wrap180(b-brg)) > 0.01 {
+			t.Errorf("bearing %v: projected bearing %f", brg, b)
+		}
+
+		ilat, ilon := p.Inverse(x, y)
+		if math.Abs(ilat-lat) > 1e-12 || math.Abs(ilon-lon) > 1e-12 {
+			t.Errorf("bearing %v: round trip %f, %f", brg, ilat, ilon)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package rnd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jacobwood27/go-dg-record/internal/geo"
+	"github.com/sgreben/piecewiselinear"
+)
+
+// Tuning for lining the timestamp clock up with the recording's
+var (
+	// Offsets up to MaxClockOffset seconds either way are tried, ClockStep
+	// seconds apart. A MaxClockOffset of 0 leaves the timestamps alone.
+	MaxClockOffset = 120.0
+	ClockStep      = 1.0
+
+	// How far in meters from a tee or pin, and in seconds from the end of a
+	// stop in the track, a throw can land and still mostly count as lined up
+	// with it
+	AnchorDist = 10.0
+	StopSlack  = 5.0
+
+	// Offsets within ClockPeakWidth seconds of the best are taken as the same
+	// answer when working out how sure it is
+	ClockPeakWidth = 30.0
+
+	// An estimate less sure than this leaves the timestamps alone
+	MinClockConfidence = 0.6
+)
+
+// Ways to set the clock offset, besides a number of seconds
+const (
+	CLOCKAUTO = "auto"
+	CLOCKOFF  = "off"
+)
+
+const ClockOffsetEnv = "DISCGOLF_CLOCK_OFFSET"
+
+var clockOffsetFlag string
+
+func init() {
+	flag.StringVar(&clockOffsetFlag, "clock-offset", "", "seconds to add to the throw times to match the recording's clock, auto to estimate it or off (default $"+ClockOffsetEnv+", then ClockOffset: in the config file, then auto)")
+}
+
+// The clock offset setting, from the -clock-offset flag, the
+// DISCGOLF_CLOCK_OFFSET environment variable or the config file, in that
+// order, and auto otherwise
+func ClockOffsetSetting() string {
+	s := clockOffsetFlag
+	if s == "" {
+		s = os.Getenv(ClockOffsetEnv)
+	}
+	if s == "" {
+		s = readConfig(ConfigFile())["ClockOffset"]
+	}
+	if s == "" {
+		s = CLOCKAUTO
+	}
+	return s
+}
+
+// The time to add to the timestamps to put them on the recording's clock.
+// Confidence runs from 0, when some other offset lines up as well, to 1, when
+// nothing else comes close.
+type ClockOffset struct {
+	Offset     time.Duration
+	Confidence float64
+}
+
+func (co ClockOffset) String() string {
+	return fmt.Sprintf("%+.0fs (confidence %.2f)", co.Offset.Seconds(), co.Confidence)
+}
+
+// The offset to add to the throw times under setting, and a line saying how
+// it was chosen. An estimate is only used when it's at least
+// MinClockConfidence sure.
+func chooseClockOffset(setting string, ts timestamps, locs locstamps, anchors []Loc) (time.Duration, string) {
+	switch setting {
+	case CLOCKOFF:
+		return 0, "Clock offset off"
+	case CLOCKAUTO:
+	default:
+		sec, err := strconv.ParseFloat(setting, 64)
+		if err == nil {
+			return time.Duration(sec * float64(time.Second)), fmt.Sprintf("Clock offset %+gs as set", sec)
+		}
+		fmt.Println("Unknown clock offset " + setting + ", estimating it")
+	}
+
+	co := estimateClockOffset(ts, locs, anchors)
+	if co.Confidence < MinClockConfidence {
+		return 0, fmt.Sprintf("Clock offset %s, below %.2f so not applied", co, MinClockConfidence)
+	}
+	return co.Offset, "Clock offset " + co.String()
+}
+
+func (ts timestamps) shift(d time.Duration) timestamps {
+	out := make(timestamps, len(ts))
+	for i, t := range ts {
+		out[i] = timestamp{t.time.Add(d), t.disc}
+	}
+	return out
+}
+
+// Tees and pins of the courses the track could be on
+func trackAnchors(locs locstamps) []Loc {
+	if len(locs) == 0 {
+		return nil
+	}
+
+	var as []Loc
+	for _, cc := range InferCourses(Loc{locs[0].lat, locs[0].lon}) {
+		for _, h := range cc.Course.Holes {
+			for _, t := range h.Tees {
+				as = append(as, t.Loc)
+			}
+			for _, p := range h.Pins {
+				as = append(as, p.Loc)
+			}
+		}
+	}
+	return as
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+// Finds the offset that best lines the throws up with the recording. Each
+// candidate is scored by how many throws it puts as the player stopped
+// standing still and how many it puts on a tee or pin in anchors.
+func estimateClockOffset(ts timestamps, locs locstamps, anchors []Loc) ClockOffset {
+	if len(ts) == 0 || len(locs) < 2 || MaxClockOffset <= 0 || ClockStep <= 0 {
+		return ClockOffset{}
+	}
+
+	p := geo.NewProjection(locs[0].lat, locs[0].lon)
+	var fx, fy piecewiselinear.Function
+	for _, l := range locs {
+		x, y := p.Forward(l.lat, l.lon)
+		fx.X = append(fx.X, unixSeconds(l.time))
+		fx.Y = append(fx.Y, x)
+		fy.X = append(fy.X, unixSeconds(l.time))
+		fy.Y = append(fy.Y, y)
+	}
+	t0, t1 := fx.X[0], fx.X[len(fx.X)-1]
+
+	var ax, ay []float64
+	for _, a := range anchors {
+		if len(a) < 2 {
+			continue
+		}
+		x, y := p.Forward(a[0], a[1])
+		ax = append(ax, x)
+		ay = append(ay, y)
+	}
+
+	type span struct {
+		from float64
+		to   float64
+	}
+	var stops []span
+	for _, d := range locs.stops() {
+		stops = append(stops, span{unixSeconds(locs[d.first].time), unixSeconds(locs[d.last-1].time)})
+	}
+
+	score := func(o float64) float64 {
+		s := 0.0
+		for _, t := range ts {
+			tt := unixSeconds(t.time) + o
+
+			// just done standing still when the throw was logged, as a
+			// throw ends a stop
+			gap := math.Inf(1)
+			for _, sp := range stops {
+				gap = math.Min(gap, math.Abs(tt-sp.to))
+			}
+			s += math.Exp(-gap * gap / (2 * StopSlack * StopSlack))
+
+			// and throwing from a tee or putting out, off the ends of the
+			// track the player is where it starts or stops
+			tt = math.Max(t0, math.Min(t1, tt))
+			x, y := fx.At(tt), fy.At(tt)
+			d := math.Inf(1)
+			for k := range ax {
+				d = math.Min(d, math.Hypot(ax[k]-x, ay[k]-y))
+			}
+			s += math.Exp(-d * d / (2 * AnchorDist * AnchorDist))
+		}
+		return s / float64(2*len(ts))
+	}
+
+	// nearest offsets first so a tie goes to the smaller one
+	offsets := []float64{0}
+	for o := ClockStep; o <= MaxClockOffset; o += ClockStep {
+		offsets = append(offsets, o, -o)
+	}
+	scores := make([]float64, len(offsets))
+	best := 0
+	for i, o := range offsets {
+		scores[i] = score(o)
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+
+	// how far the best stands out of the typical score compared to the best
+	// away from its peak
+	sorted := append([]float64{}, scores...)
+	sort.Float64s(sorted)
+	typical := sorted[len(sorted)/2]
+	rival := typical
+	for i, o := range offsets {
+		if math.Abs(o-offsets[best]) > ClockPeakWidth {
+			rival = math.Max(rival, scores[i])
+		}
+	}
+
+	co := ClockOffset{Offset: time.Duration(offsets[best] * float64(time.Second))}
+	if scores[best] > typical {
+		co.Confidence = (scores[best] - rival) / (scores[best] - typical)
+	}
+	return co
+}
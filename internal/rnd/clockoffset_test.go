@@ -0,0 +1,122 @@
+package rnd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// A player playing four holes of different lengths, standing a while on
+// each tee, for each upshot and at each pin, and throwing just before moving
+// on, a fix a second. Returns the track, the true throw times and the tees
+// and pins.
+func playedTrack() (locstamps, []time.Time, []Loc) {
+	var locs locstamps
+	var throws []time.Time
+	var anchors []Loc
+	s := 0
+	stand := func(l Loc, secs int) {
+		for i := 0; i < secs; i++ {
+			locs = append(locs, locstamp{time: trackStart.Add(time.Duration(s) * time.Second), lat: l[0], lon: l[1], acc: 3})
+			s++
+		}
+	}
+	throw := func(l Loc, secs int) {
+		stand(l, secs)
+		throws = append(throws, trackStart.Add(time.Duration(s-2)*time.Second))
+	}
+	walk := func(from Loc, to Loc) {
+		d := Dist(from, to)
+		n := int(d / 1.3)
+		for i := 1; i < n; i++ {
+			stand(Destination(from, Bearing(from, to), d*float64(i)/float64(n)), 1)
+		}
+	}
+
+	holes := []struct {
+		e      float64
+		length float64
+		lies   []float64 // how far along each upshot is thrown from
+		waits  []int     // seconds on the tee, at each lie and at the pin
+	}{
+		{0, 80, nil, []int{45, 15}},
+		{60, 150, []float64{110}, []int{20, 25, 30}},
+		{120, 220, []float64{120, 190}, []int{70, 15, 20, 12}},
+		{200, 110, []float64{95}, []int{25, 40, 18}},
+	}
+	for _, h := range holes {
+		tee, pin := at(h.e, 0), at(h.e, h.length)
+		anchors = append(anchors, tee, pin)
+
+		lie := tee
+		for k, along := range h.lies {
+			throw(lie, h.waits[k])
+			next := at(h.e+5, along)
+			walk(lie, next)
+			lie = next
+		}
+		throw(lie, h.waits[len(h.waits)-2])
+		walk(lie, pin)
+		throw(pin, h.waits[len(h.waits)-1])
+		walk(pin, at(h.e+60, 0))
+	}
+	return locs, throws, anchors
+}
+
+// Throw times on a clock that's off seconds behind the recording's
+func loggedThrows(throws []time.Time, off float64) timestamps {
+	var ts timestamps
+	for _, t := range throws {
+		ts = append(ts, timestamp{t.Add(-time.Duration(off * float64(time.Second))), "D"})
+	}
+	return ts
+}
+
+func TestEstimateClockOffset(t *testing.T) {
+	locs, throws, anchors := playedTrack()
+
+	for _, off := range []float64{0, 8, -25, 47, -90} {
+		co := estimateClockOffset(loggedThrows(throws, off), locs, anchors)
+		if math.Abs(co.Offset.Seconds()-off) > 2 {
+			t.Errorf("offset %+.0fs: estimated %s", off, co)
+		}
+		if co.Confidence < MinClockConfidence {
+			t.Errorf("offset %+.0fs: estimated %s, too unsure to apply", off, co)
+		}
+	}
+}
+
+func TestChooseClockOffset(t *testing.T) {
+	locs, throws, anchors := playedTrack()
+	ts := loggedThrows(throws, 40)
+
+	tests := []struct {
+		setting string
+		want    float64
+	}{
+		{CLOCKAUTO, 40},
+		{CLOCKOFF, 0},
+		{"12", 12},
+		{"-3.5", -3.5},
+		{"sometimes", 40},
+	}
+	for _, tt := range tests {
+		off, msg := chooseClockOffset(tt.setting, ts, locs, anchors)
+		if math.Abs(off.Seconds()-tt.want) > 2 {
+			t.Errorf("%s: offset %v, want %+.1fs", tt.setting, off, tt.want)
+		}
+		if msg == "" {
+			t.Errorf("%s: nothing said about the offset", tt.setting)
+		}
+	}
+
+	// walking steadily the whole way, nothing to line the throws up with
+	var walk locstamps
+	for i := 0; i < 600; i++ {
+		l := at(0, 1.3*float64(i))
+		walk = append(walk, locstamp{time: trackStart.Add(time.Duration(i) * time.Second), lat: l[0], lon: l[1], acc: 3})
+	}
+	if off, msg := chooseClockOffset(CLOCKAUTO, ts, walk, nil); off != 0 {
+		t.Errorf("unsure estimate applied: %s", msg)
+	}
+}
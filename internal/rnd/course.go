@@ -6,7 +6,9 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
-	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 type Properties struct {
@@ -15,6 +17,7 @@ type Properties struct {
 	Name     string `json:"name"`
 	Par      int    `json:"par"`
 	Result   int    `json:"res"`
+	Penalty  int    `json:"penalty,omitempty"`
 }
 
 type Geometry struct {
@@ -29,19 +32,164 @@ type Feature struct {
 }
 
 type SummaryTableRow struct {
-	Hole string  `json:"hole"`
-	Tee  string  `json:"tee"`
-	Pin  string  `json:"pin"`
-	Dist float64 `json:"dist"`
-	Par  int     `json:"par"`
+	Hole      string  `json:"hole"`
+	Tee       string  `json:"tee"`
+	Pin       string  `json:"pin"`
+	Dist      float64 `json:"dist"`
+	Elev      float64 `json:"elev"`
+	PlaysLike float64 `json:"plays_like"`
+	Par       int     `json:"par"`
+	Played    int     `json:"played"`
+	Average   float64 `json:"avg"`
+	StdDev    float64 `json:"sd"`
+	Spread    string  `json:"spread"`
+	Rank      int     `json:"rank"`
 }
 
 type SummaryTable []SummaryTableRow
 
+type LayoutSummary struct {
+	ID             string  `json:"id"`
+	Name           string  `json:"name"`
+	Par            int     `json:"par"`
+	Length         float64 `json:"length"`
+	Rounds         int     `json:"rounds"`
+	ScratchAverage float64 `json:"ssa"`
+}
+
+// A pin rotation as edit-course shows it, with the pins each hole could have
+type RotationSummary struct {
+	From  string         `json:"from"`
+	To    string         `json:"to"`
+	Holes []RotationHole `json:"holes"`
+}
+
+type RotationHole struct {
+	Hole string   `json:"hole"`
+	Pin  string   `json:"pin"`
+	Pins []string `json:"pins"`
+}
+
 type CourseGEOJSON struct {
-	Type     string       `json:"type"`
-	Features []Feature    `json:"features"`
-	Table    SummaryTable `json:"table"`
+	Type      string            `json:"type"`
+	Features  []Feature         `json:"features"`
+	Table     SummaryTable      `json:"table"`
+	Layouts   []LayoutSummary   `json:"layouts"`
+	Warnings  []string          `json:"warnings"`
+	Conflicts []string          `json:"conflicts"`
+	Rotations []RotationSummary `json:"rotations"`
+	Units     string            `json:"units"`
+}
+
+func pathCoords(path []Loc) [][]float64 {
+	var coords [][]float64
+	for _, l := range path {
+		coords = append(coords, []float64{l[1], l[0]})
+	}
+	return coords
+}
+
+func WaypointName(hID string, tID string, pID string, k int) string {
+	return hID + "_" + tID + "->" + pID + "#" + strconv.Itoa(k)
+}
+
+func (c Course) hazardFeatures() []Feature {
+	var features []Feature
+	for _, h := range c.Holes {
+		for _, z := range h.Hazards {
+			ring := pathCoords(z.Poly)
+			if len(ring) > 0 {
+				ring = append(ring, ring[0])
+			}
+			geom := Geometry{
+				Type:        "Polygon",
+				Coordinates: [][][]float64{ring},
+			}
+			f := Feature{
+				Type: "Feature",
+				Properties: Properties{
+					Thing: z.Type,
+					Name:  h.ID + "_" + z.ID,
+				},
+				Geometry: geom,
+			}
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+func (c Course) mandoFeatures() []Feature {
+	var features []Feature
+	for _, h := range c.Holes {
+		for _, m := range h.Mandos {
+			geom := Geometry{
+				Type:        "LineString",
+				Coordinates: pathCoords(m.Line),
+			}
+			f := Feature{
+				Type: "Feature",
+				Properties: Properties{
+					Thing: "mando",
+					Name:  h.ID + "_" + m.ID + "_" + m.Side,
+				},
+				Geometry: geom,
+			}
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+func (c Course) dropZoneFeatures() []Feature {
+	var features []Feature
+	for _, h := range c.Holes {
+		for _, d := range h.AllDropZones() {
+			geom := Geometry{
+				Type:        "Point",
+				Coordinates: []float64{d.Loc[1], d.Loc[0]},
+			}
+			f := Feature{
+				Type: "Feature",
+				Properties: Properties{
+					Thing: "dropzone",
+					Name:  h.ID + "_" + d.ID,
+				},
+				Geometry: geom,
+			}
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// Their side of each merge conflict, named by its index in c.Conflicts
+func (c Course) conflictFeatures() []Feature {
+	var features []Feature
+	for i, mc := range c.Conflicts {
+		geom := Geometry{
+			Type:        "LineString",
+			Coordinates: pathCoords(mc.Path),
+		}
+		if len(mc.Loc) >= 2 {
+			geom = Geometry{
+				Type:        "Point",
+				Coordinates: []float64{mc.Loc[1], mc.Loc[0]},
+			}
+		} else if len(mc.Path) < 2 {
+			continue
+		}
+		f := Feature{
+			Type: "Feature",
+			Properties: Properties{
+				Thing: "conflict",
+				Name:  strconv.Itoa(i),
+			},
+			Geometry: geom,
+		}
+		features = append(features, f)
+	}
+	return features
 }
 
 func (c Course) DrawSummary() {
@@ -66,7 +214,7 @@ func (c Course) DrawSummary() {
 			for _, p := range h.Pins {
 				geom := Geometry{
 					Type:        "LineString",
-					Coordinates: [][]float64{{t.Loc[1], t.Loc[0]}, {p.Loc[1], p.Loc[0]}},
+					Coordinates: pathCoords(h.Path(t.ID, p.ID)),
 				}
 				f := Feature{
 					Type: "Feature",
@@ -78,6 +226,22 @@ func (c Course) DrawSummary() {
 					Geometry: geom,
 				}
 				features = append(features, f)
+
+				for k, w := range h.Waypoints(t.ID, p.ID) {
+					geom := Geometry{
+						Type:        "Point",
+						Coordinates: []float64{w[1], w[0]},
+					}
+					f := Feature{
+						Type: "Feature",
+						Properties: Properties{
+							Thing: "waypoint",
+							Name:  WaypointName(h.ID, t.ID, p.ID, k),
+						},
+						Geometry: geom,
+					}
+					features = append(features, f)
+				}
 			}
 		}
 
@@ -98,6 +262,14 @@ func (c Course) DrawSummary() {
 		}
 	}
 
+	features = append(features, c.hazardFeatures()...)
+	features = append(features, c.mandoFeatures()...)
+	features = append(features, c.dropZoneFeatures()...)
+	features = append(features, c.conflictFeatures()...)
+
+	// scoring history from the last make-stats run
+	cs, _ := LoadCourseStats(c.ID)
+
 	var tRows []SummaryTableRow
 	for _, h := range c.Holes {
 		for i, t := range h.Tees {
@@ -112,26 +284,70 @@ func (c Course) DrawSummary() {
 					teename = t.ID
 				}
 				par := h.Par(t.ID, p.ID)
+				hs, _ := cs.GetHole(h.ID, t.ID, p.ID)
 
 				tRows = append(tRows, SummaryTableRow{
-					Hole: holename,
-					Tee:  teename,
-					Pin:  pinname,
-					Dist: math.Round(Dist(t.Loc, p.Loc) * 3.28),
-					Par:  par,
+					Hole:      holename,
+					Tee:       teename,
+					Pin:       pinname,
+					Dist:      InUnits(h.Length(t.ID, p.ID)),
+					Elev:      InUnits(h.Elevation(t.ID, p.ID)),
+					PlaysLike: InUnits(h.PlaysLike(t.ID, p.ID)),
+					Par:       par,
+					Played:    hs.Played,
+					Average:   math.Round(hs.Average*100) / 100,
+					StdDev:    math.Round(hs.StdDev*100) / 100,
+					Spread:    hs.Spread(),
+					Rank:      hs.Rank,
 				})
 			}
 		}
 	}
 
+	var lRows []LayoutSummary
+	for _, l := range c.Layouts {
+		ls, _ := cs.GetLayout(l.ID)
+		lRows = append(lRows, LayoutSummary{
+			ID:             l.ID,
+			Name:           l.Name,
+			Par:            l.Par(c),
+			Length:         InUnits(l.Length(c)),
+			Rounds:         ls.Rounds,
+			ScratchAverage: ls.ScratchAverage,
+		})
+	}
+
 	cgj := CourseGEOJSON{
 		Type:     "FeatureCollection",
 		Features: features,
 		Table:    SummaryTable(tRows),
+		Layouts:  lRows,
+		Warnings: c.Validate(),
+		Units:    Units(),
+	}
+	for _, mc := range c.Conflicts {
+		cgj.Conflicts = append(cgj.Conflicts, mc.String())
+	}
+	for _, pr := range c.Rotations {
+		rs := RotationSummary{From: pr.From, To: pr.To}
+		for _, h := range c.Holes {
+			rh := RotationHole{Hole: h.ID, Pin: pr.Pins[h.ID]}
+			for _, p := range h.Pins {
+				rh.Pins = append(rh.Pins, p.ID)
+			}
+			rs.Holes = append(rs.Holes, rh)
+		}
+		cgj.Rotations = append(cgj.Rotations, rs)
 	}
 
 	file, _ := json.MarshalIndent(cgj, "", "	")
-	_ = ioutil.WriteFile("course_vis.json", file, 0644)
+	_ = ioutil.WriteFile(filepath.Join(VisDir(), "course_vis.json"), file, 0644)
+}
+
+func parseCourse(b []byte) Course {
+	var crs Course
+	json.Unmarshal(b, &crs)
+	return crs
 }
 
 func ParseCourseJSON(filename string) Course {
@@ -143,40 +359,45 @@ func ParseCourseJSON(filename string) Course {
 
 	byteValue, _ := ioutil.ReadAll(jsonFile)
 
-	var crs Course
-	json.Unmarshal([]byte(byteValue), &crs)
-
-	return crs
+	return parseCourse(byteValue)
 }
 
-func (c Course) SaveCourseJSON() {
+func (c Course) SaveCourse() {
 	file, _ := json.MarshalIndent(c, "", "	")
-	_ = ioutil.WriteFile(c.ID+".json", file, 0644)
+	if err := DefaultStore().WriteCourse(c.ID, file); err != nil {
+		fmt.Println(err)
+	}
 }
 
 func inferCourse(l Loc) Course {
-	crs_path := path.Join(RootDir(), "..", "data", "courses")
-	files, e := os.ReadDir(crs_path)
-	if e != nil {
-		panic(e)
+	cands := InferCourses(l)
+	if len(cands) == 0 {
+		return Course{}
 	}
+	return cands[0].Course
+}
 
-	best_c := Course{}
-	best_dist := 999.9
-	for _, file := range files {
-		c := ParseCourseJSON(path.Join(crs_path, file.Name()))
-		this_dist := Dist(c.Loc, l)
-
-		if this_dist < best_dist {
-			best_c = c
-			best_dist = this_dist
-		}
+func GetCourse(courseID string) Course {
+	b, err := DefaultStore().ReadCourse(courseID)
+	if err != nil {
+		fmt.Println(courseID + ": " + err.Error())
+		return Course{}
 	}
+	return parseCourse(b)
+}
 
-	return best_c
+// A course JSON file, or the ID of a course in the store
+func LoadCourse(arg string) Course {
+	if strings.HasSuffix(arg, ".json") {
+		return ParseCourseJSON(arg)
+	}
+	return GetCourse(arg)
 }
 
-func GetCourse(courseID string) Course {
-	crs_path := path.Join(RootDir(), "..", "data", "courses")
-	return ParseCourseJSON(path.Join(crs_path, courseID+".json"))
+func GetCourseIDs() []string {
+	ids, err := DefaultStore().CourseIDs()
+	if err != nil {
+		fmt.Println(err)
+	}
+	return ids
 }
@@ -0,0 +1,222 @@
+package rnd
+
+import (
+	"math"
+	"sort"
+)
+
+// Courses closer than this to the best match are offered as alternatives
+var AmbiguousDist = 300.0
+
+// Points this close outside a course hull still count as on the course
+var HullMargin = 30.0
+
+// Points further than this in meters from every course are on none of them
+var MaxCourseDist = 3000.0
+
+type CourseCandidate struct {
+	Course Course  `json:"-"`
+	ID     string  `json:"id"`
+	Inside bool    `json:"inside"`
+	Dist   float64 `json:"dist"`
+	Score  float64 `json:"score"`
+}
+
+type indexEntry struct {
+	course Course
+	points []Loc
+	hull   []Loc
+
+	// bounding box, lat then lon
+	min Loc
+	max Loc
+}
+
+type CourseIndex struct {
+	entries []indexEntry
+}
+
+func cross(o Loc, a Loc, b Loc) float64 {
+	ax, ay := localXY(a, o)
+	bx, by := localXY(b, o)
+	return ax*by - ay*bx
+}
+
+// Convex hull by the monotone chain, counter-clockwise
+func convexHull(pts []Loc) []Loc {
+	if len(pts) < 3 {
+		return pts
+	}
+
+	p := append([]Loc{}, pts...)
+	sort.Slice(p, func(i, j int) bool {
+		if p[i][1] == p[j][1] {
+			return p[i][0] < p[j][0]
+		}
+		return p[i][1] < p[j][1]
+	})
+
+	var hull []Loc
+	for _, l := range p {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], l) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, l)
+	}
+	lower := len(hull) + 1
+	for i := len(p) - 2; i >= 0; i-- {
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], p[i]) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p[i])
+	}
+	return hull[:len(hull)-1]
+}
+
+func newIndexEntry(c Course) indexEntry {
+	e := indexEntry{course: c}
+	for _, h := range c.Holes {
+		for _, t := range h.Tees {
+			if len(t.Loc) >= 2 {
+				e.points = append(e.points, t.Loc)
+			}
+		}
+		for _, p := range h.Pins {
+			if len(p.Loc) >= 2 {
+				e.points = append(e.points, p.Loc)
+			}
+		}
+		for _, f := range h.Fairways {
+			e.points = append(e.points, f.Path...)
+		}
+	}
+	if len(e.points) == 0 && len(c.Loc) >= 2 {
+		e.points = []Loc{c.Loc}
+	}
+	if len(e.points) == 0 {
+		return e
+	}
+
+	e.hull = convexHull(e.points)
+	e.min = Loc{e.points[0][0], e.points[0][1]}
+	e.max = Loc{e.points[0][0], e.points[0][1]}
+	for _, l := range e.points {
+		e.min[0] = math.Min(e.min[0], l[0])
+		e.min[1] = math.Min(e.min[1], l[1])
+		e.max[0] = math.Max(e.max[0], l[0])
+		e.max[1] = math.Max(e.max[1], l[1])
+	}
+	return e
+}
+
+// Meters from l to the course area, 0 when inside it
+func (e indexEntry) dist(l Loc) float64 {
+	if len(e.hull) >= 3 && inPoly(l, e.hull) {
+		return 0.0
+	}
+
+	best := math.Inf(1)
+	for i := range e.hull {
+		j := (i + 1) % len(e.hull)
+		best = math.Min(best, segDist(l, e.hull[i], e.hull[j]))
+	}
+	return best
+}
+
+// Rough meters from l to the bounding box, for skipping far away courses
+func (e indexEntry) boxDist(l Loc) float64 {
+	c := Loc{
+		math.Max(e.min[0], math.Min(e.max[0], l[0])),
+		math.Max(e.min[1], math.Min(e.max[1], l[1])),
+	}
+	x, y := localXY(c, l)
+	return math.Hypot(x, y)
+}
+
+func (e indexEntry) nearest(l Loc) float64 {
+	best := math.Inf(1)
+	for _, p := range e.points {
+		best = math.Min(best, Dist(p, l))
+	}
+	return best
+}
+
+func NewCourseIndex(courses []Course) CourseIndex {
+	var ci CourseIndex
+	for _, c := range courses {
+		e := newIndexEntry(c)
+		if len(e.points) > 0 {
+			ci.entries = append(ci.entries, e)
+		}
+	}
+	return ci
+}
+
+// Courses that l could be on, best first. Being inside a course area beats
+// being near one, and the best course is followed by every other course
+// within AmbiguousDist. There are none if l is more than MaxCourseDist from
+// every course.
+func (ci CourseIndex) Candidates(l Loc) []CourseCandidate {
+	var cands []CourseCandidate
+	best_dist := math.Inf(1)
+	for _, e := range ci.entries {
+		if e.boxDist(l) > MaxCourseDist {
+			continue
+		}
+		d := e.dist(l)
+		best_dist = math.Min(best_dist, d)
+	}
+	if best_dist > MaxCourseDist {
+		return nil
+	}
+
+	for _, e := range ci.entries {
+		if e.boxDist(l) > best_dist+AmbiguousDist+HullMargin {
+			continue
+		}
+		d := e.dist(l)
+		if d > best_dist+AmbiguousDist {
+			continue
+		}
+
+		inside := d <= HullMargin
+		score := 1.0 / (1.0 + e.nearest(l)/100.0)
+		if inside {
+			score += 1.0
+		}
+		cands = append(cands, CourseCandidate{
+			Course: e.course,
+			ID:     e.course.ID,
+			Inside: inside,
+			Dist:   d,
+			Score:  score,
+		})
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].Score > cands[j].Score
+	})
+	return cands
+}
+
+var courseIndex *CourseIndex
+
+// Index of the current layout of every course in the store, built once
+func GetCourseIndex() CourseIndex {
+	if courseIndex != nil {
+		return *courseIndex
+	}
+
+	var courses []Course
+	for _, id := range GetCourseIDs() {
+		courses = append(courses, GetCourse(id))
+	}
+
+	ci := NewCourseIndex(courses)
+	courseIndex = &ci
+	return ci
+}
+
+func InferCourses(l Loc) []CourseCandidate {
+	return GetCourseIndex().Candidates(l)
+}
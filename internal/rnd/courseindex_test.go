@@ -0,0 +1,46 @@
+package rnd
+
+import "testing"
+
+func TestCandidates(t *testing.T) {
+	near := testCourse()
+	other := testCourse()
+	other.ID = "other"
+	for i := range other.Holes {
+		h := &other.Holes[i]
+		for j := range h.Tees {
+			h.Tees[j].Loc = Destination(h.Tees[j].Loc, 90, 1000)
+		}
+		for j := range h.Pins {
+			h.Pins[j].Loc = Destination(h.Pins[j].Loc, 90, 1000)
+		}
+	}
+	ci := NewCourseIndex([]Course{near, other})
+
+	cases := []struct {
+		name string
+		l    Loc
+		want []string
+	}{
+		{"on the course", at(0, 50), []string{"test"}},
+		{"between the two", at(400, 50), []string{"test", "other"}},
+		{"next town over", at(0, 20000), nil},
+	}
+	for _, c := range cases {
+		cands := ci.Candidates(c.l)
+		var got []string
+		for _, cc := range cands {
+			got = append(got, cc.ID)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: candidates %v, want %v", c.name, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: candidates %v, want %v", c.name, got, c.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package rnd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Scoring history of one tee/pin of a hole
+type HoleStats struct {
+	Hole    string      `json:"hole"`
+	Tee     string      `json:"tee"`
+	Pin     string      `json:"pin"`
+	Par     int         `json:"par"`
+	Played  int         `json:"played"`
+	Average float64     `json:"avg"`
+	StdDev  float64     `json:"sd"`
+	Scores  map[int]int `json:"scores"`
+	Rank    int         `json:"rank"`
+}
+
+// Scoring history of a layout. The scratch scoring average is the sum of
+// the averages on the layout's tee and pin of each hole, so rounds that
+// skipped a hole still count. Rounds that didn't match a layout of the course
+// are kept under layout "" with no scratch average or par, as they weren't
+// all played from the same tees to the same pins.
+type LayoutStats struct {
+	Layout         string      `json:"layout"`
+	Rounds         int         `json:"rounds"`
+	ScratchAverage float64     `json:"ssa"`
+	Par            int         `json:"par"`
+	Holes          []HoleStats `json:"holes"`
+}
+
+type CourseStats struct {
+	CourseID string `json:"courseID"`
+	Rounds   int    `json:"rounds"`
+
+	// every tee/pin played, whatever the layout
+	Holes   []HoleStats   `json:"holes"`
+	Layouts []LayoutStats `json:"layouts"`
+}
+
+func (hs HoleStats) key() string {
+	return hs.Hole + "_" + hs.Tee + "->" + hs.Pin
+}
+
+// Score counts like "2x3 3x10 4x1", fewest strokes first
+func (hs HoleStats) Spread() string {
+	var strokes []int
+	for s := range hs.Scores {
+		strokes = append(strokes, s)
+	}
+	sort.Ints(strokes)
+
+	var parts []string
+	for _, s := range strokes {
+		parts = append(parts, strconv.Itoa(s)+"x"+strconv.Itoa(hs.Scores[s]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Averages and spreads for every tee/pin in the scores, ranked hardest
+// (most strokes over par) first
+func holeStats(scores []HoleScoreSummary) []HoleStats {
+	var HS []HoleStats
+	idx := map[string]int{}
+	for _, s := range scores {
+		hs := HoleStats{Hole: s.Hole, Tee: s.Tee, Pin: s.Pin, Par: s.Par}
+		i, ok := idx[hs.key()]
+		if !ok {
+			hs.Scores = map[int]int{}
+			HS = append(HS, hs)
+			i = len(HS) - 1
+			idx[hs.key()] = i
+		}
+		HS[i].Played++
+		HS[i].Scores[s.Score]++
+		HS[i].Average += float64(s.Score)
+	}
+
+	for i := range HS {
+		HS[i].Average /= float64(HS[i].Played)
+
+		v := 0.0
+		for s, n := range HS[i].Scores {
+			v += float64(n) * math.Pow(float64(s)-HS[i].Average, 2)
+		}
+		if HS[i].Played > 1 {
+			HS[i].StdDev = math.Sqrt(v / float64(HS[i].Played-1))
+		}
+	}
+
+	byDifficulty := make([]int, len(HS))
+	for i := range byDifficulty {
+		byDifficulty[i] = i
+	}
+	sort.SliceStable(byDifficulty, func(a, b int) bool {
+		ha, hb := HS[byDifficulty[a]], HS[byDifficulty[b]]
+		da, db := ha.Average-float64(ha.Par), hb.Average-float64(hb.Par)
+		if da != db {
+			return da > db
+		}
+		return ha.StdDev > hb.StdDev
+	})
+	for r, i := range byDifficulty {
+		HS[i].Rank = r + 1
+	}
+
+	return HS
+}
+
+// Scoring stats for a course from every round played on it
+func GetCourseStats(c Course, rounds []Round) CourseStats {
+	cs := CourseStats{CourseID: c.ID}
+
+	var all []HoleScoreSummary
+	var layouts []string
+	byLayout := map[string][]HoleScoreSummary{}
+	nRounds := map[string]int{}
+	for _, r := range rounds {
+		if r.CourseID != c.ID || len(r.Data) == 0 {
+			continue
+		}
+		scores := r.HoleScores()
+		all = append(all, scores...)
+		if _, ok := byLayout[r.LayoutID]; !ok {
+			layouts = append(layouts, r.LayoutID)
+		}
+		byLayout[r.LayoutID] = append(byLayout[r.LayoutID], scores...)
+		nRounds[r.LayoutID]++
+		cs.Rounds++
+	}
+
+	cs.Holes = holeStats(all)
+
+	sort.Strings(layouts)
+	for _, l := range layouts {
+		ls := LayoutStats{
+			Layout: l,
+			Rounds: nRounds[l],
+			Holes:  holeStats(byLayout[l]),
+		}
+		if lay := c.GetLayout(l); lay.ID != "" {
+			ls.ScratchAverage = math.Round(layoutAverage(c, lay, ls.Holes)*10) / 10
+			ls.Par = lay.Par(c)
+		}
+		cs.Layouts = append(cs.Layouts, ls)
+	}
+
+	return cs
+}
+
+// Sum of the average score on each hole of l. Where the layout's tee and pin
+// of a hole weren't played the other ways it was played stand in for them,
+// and a hole never played counts as par.
+func layoutAverage(c Course, l Layout, holes []HoleStats) float64 {
+	sum := 0.0
+	for _, lh := range l.Holes {
+		avg := float64(c.GetHole(lh.Hole).Par(lh.Tee, lh.Pin))
+		n, tot := 0, 0.0
+		for _, hs := range holes {
+			if hs.Hole != lh.Hole {
+				continue
+			}
+			if hs.Tee == lh.Tee && hs.Pin == lh.Pin {
+				n, tot = hs.Played, hs.Average*float64(hs.Played)
+				break
+			}
+			n += hs.Played
+			tot += hs.Average * float64(hs.Played)
+		}
+		if n > 0 {
+			avg = tot / float64(n)
+		}
+		sum += avg
+	}
+	return sum
+}
+
+func courseStatsName(courseID string) string {
+	return "course_stats_" + courseID + ".json"
+}
+
+func (cs CourseStats) Save() {
+	file, _ := json.MarshalIndent(cs, "", "	")
+	if err := DefaultStore().WriteStats(courseStatsName(cs.CourseID), file); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// The stats make-stats last saved for a course, if any
+func LoadCourseStats(courseID string) (CourseStats, bool) {
+	var cs CourseStats
+	b, err := DefaultStore().ReadStats(courseStatsName(courseID))
+	if err != nil {
+		return cs, false
+	}
+	if err := json.Unmarshal(b, &cs); err != nil {
+		fmt.Println(err)
+		return cs, false
+	}
+	return cs, true
+}
+
+func (cs CourseStats) GetHole(hID string, tID string, pID string) (HoleStats, bool) {
+	for _, hs := range cs.Holes {
+		if hs.Hole == hID && hs.Tee == tID && hs.Pin == pID {
+			return hs, true
+		}
+	}
+	return HoleStats{}, false
+}
+
+func (cs CourseStats) GetLayout(lID string) (LayoutStats, bool) {
+	for _, ls := range cs.Layouts {
+		if ls.Layout == lID {
+			return ls, true
+		}
+	}
+	return LayoutStats{}, false
+}
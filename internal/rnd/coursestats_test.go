@@ -0,0 +1,93 @@
+package rnd
+
+import "testing"
+
+// Hole 1 has a short and a long tee, hole 2 a single tee
+func twoTeeCourse() Course {
+	return Course{
+		ID:  "twotee",
+		Loc: testTee,
+		Holes: []Hole{
+			{
+				ID:   "1",
+				Tees: []Tee{{ID: "short", Loc: at(0, 0)}, {ID: "long", Loc: at(0, -50)}},
+				Pins: []Pin{{ID: "A", Loc: at(0, 100)}},
+				Pars: []Par{{Tee: "short", Pin: "A", Par: 3}, {Tee: "long", Pin: "A", Par: 4}},
+			},
+			{
+				ID:   "2",
+				Tees: []Tee{{ID: "reg", Loc: at(20, 100)}},
+				Pins: []Pin{{ID: "A", Loc: at(20, 200)}},
+				Pars: []Par{{Tee: "reg", Pin: "A", Par: 3}},
+			},
+		},
+		Layouts: []Layout{
+			{ID: "short", Holes: []LayoutHole{{Hole: "1", Tee: "short", Pin: "A"}, {Hole: "2", Tee: "reg", Pin: "A"}}},
+			{ID: "long", Holes: []LayoutHole{{Hole: "1", Tee: "long", Pin: "A"}, {Hole: "2", Tee: "reg", Pin: "A"}}},
+		},
+	}
+}
+
+type playedHole struct {
+	hole  string
+	tee   string
+	score int
+}
+
+// A round with score throws and a basket row on each hole
+func playedRound(c Course, layout string, holes ...playedHole) Round {
+	r := Round{CourseID: c.ID, Course: c, LayoutID: layout}
+	for _, ph := range holes {
+		for i := 0; i <= ph.score; i++ {
+			r.Data = append(r.Data, RoundRow{HoleID: ph.hole, TeeID: ph.tee, PinID: "A"})
+		}
+	}
+	return r
+}
+
+func TestCourseStatsLayouts(t *testing.T) {
+	c := twoTeeCourse()
+	rounds := []Round{
+		playedRound(c, "short", playedHole{"1", "short", 3}, playedHole{"2", "reg", 4}),
+		playedRound(c, "short", playedHole{"1", "short", 5}, playedHole{"2", "reg", 2}),
+		// the short tee was closed that day
+		playedRound(c, "short", playedHole{"1", "long", 6}, playedHole{"2", "reg", 3}),
+		playedRound(c, "long", playedHole{"1", "long", 5}, playedHole{"2", "reg", 3}),
+		playedRound(c, "", playedHole{"1", "short", 3}),
+	}
+
+	cs := GetCourseStats(c, rounds)
+	if cs.Rounds != 5 {
+		t.Errorf("%d rounds, want 5", cs.Rounds)
+	}
+	if hs, ok := cs.GetHole("1", "long", "A"); !ok || hs.Played != 2 || hs.Average != 5.5 {
+		t.Errorf("hole 1 from the long tee: %+v", hs)
+	}
+
+	tests := []struct {
+		layout string
+		ssa    float64
+		par    int
+	}{
+		{"short", 7, 6},
+		{"long", 8, 7},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		ls, ok := cs.GetLayout(tt.layout)
+		if !ok {
+			t.Errorf("no stats for layout %q", tt.layout)
+			continue
+		}
+		if ls.ScratchAverage != tt.ssa || ls.Par != tt.par {
+			t.Errorf("layout %q: ssa %.1f par %d, want %.1f %d", tt.layout, ls.ScratchAverage, ls.Par, tt.ssa, tt.par)
+		}
+	}
+
+	// a layout hole nobody has played from its tee goes by the other tees
+	l := Layout{ID: "mixed", Holes: []LayoutHole{{Hole: "1", Tee: "short", Pin: "A"}, {Hole: "2", Tee: "reg", Pin: "A"}}}
+	long, _ := cs.GetLayout("long")
+	if got := layoutAverage(c, l, long.Holes); got != 8 {
+		t.Errorf("stand in average %.1f, want 8", got)
+	}
+}
@@ -0,0 +1,391 @@
+package rnd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+type CourseChange struct {
+	Hole   string  `json:"hole"`
+	Kind   string  `json:"kind"`
+	ID     string  `json:"id"`
+	Change string  `json:"change"`
+	Dist   float64 `json:"dist"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+}
+
+func (cc CourseChange) String() string {
+	s := "hole " + cc.Hole + ": " + cc.Kind
+	if cc.ID != "" {
+		s += " " + cc.ID
+	}
+	s += " " + cc.Change
+	switch cc.Change {
+	case "moved":
+		s += fmt.Sprintf(" %.1f m", cc.Dist)
+	case "changed":
+		s += " " + cc.From + " -> " + cc.To
+	case "renamed":
+		s += fmt.Sprintf(" %s -> %s, %.1f m apart", cc.From, cc.To, cc.Dist)
+	}
+	return s
+}
+
+// Points closer than this are considered unmoved
+var moveThresh = 0.5
+
+func sameLocs(a []Loc, b []Loc) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if Dist(a[i], b[i]) > moveThresh {
+			return false
+		}
+	}
+	return true
+}
+
+// Unmatched holes, tees and pins of two surveys closer than these are taken
+// to be the same thing under a different ID
+var (
+	HoleMatchDist  = 40.0
+	PointMatchDist = 15.0
+)
+
+// Pairs the IDs of b with those of a, by ID where they agree and otherwise
+// by distance, closest first. Returns the a ID for each matched b ID.
+func matchIDs(aIDs []string, aLocs []Loc, bIDs []string, bLocs []Loc, thresh float64) map[string]string {
+	m := make(map[string]string)
+	used := make(map[string]bool)
+	for _, bID := range bIDs {
+		for _, aID := range aIDs {
+			if aID == bID {
+				m[bID] = aID
+				used[aID] = true
+			}
+		}
+	}
+
+	type pair struct {
+		a string
+		b string
+		d float64
+	}
+	var pairs []pair
+	for i, bID := range bIDs {
+		if _, ok := m[bID]; ok {
+			continue
+		}
+		for j, aID := range aIDs {
+			if used[aID] || len(aLocs[j]) < 2 || len(bLocs[i]) < 2 {
+				continue
+			}
+			if d := Dist(aLocs[j], bLocs[i]); d <= thresh {
+				pairs = append(pairs, pair{aID, bID, d})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].d < pairs[j].d
+	})
+
+	for _, p := range pairs {
+		if _, ok := m[p.b]; ok || used[p.a] {
+			continue
+		}
+		m[p.b] = p.a
+		used[p.a] = true
+	}
+	return m
+}
+
+// Middle of the tees and pins of a hole
+func (h Hole) center() Loc {
+	var lat, lon float64
+	n := 0.0
+	for _, t := range h.Tees {
+		lat, lon, n = lat+t.Loc[0], lon+t.Loc[1], n+1
+	}
+	for _, p := range h.Pins {
+		lat, lon, n = lat+p.Loc[0], lon+p.Loc[1], n+1
+	}
+	if n == 0 {
+		return Loc{}
+	}
+	return Loc{lat / n, lon / n}
+}
+
+func rename(m map[string]string, id string) string {
+	if n, ok := m[id]; ok {
+		return n
+	}
+	return id
+}
+
+// A copy of h under the given IDs
+func (h Hole) renamed(hID string, tees map[string]string, pins map[string]string) Hole {
+	r := h
+	r.ID = hID
+	r.Tees = nil
+	for _, t := range h.Tees {
+		r.Tees = append(r.Tees, Tee{ID: rename(tees, t.ID), Loc: t.Loc})
+	}
+	r.Pins = nil
+	for _, p := range h.Pins {
+		r.Pins = append(r.Pins, Pin{ID: rename(pins, p.ID), Loc: p.Loc})
+	}
+	r.Pars = nil
+	for _, p := range h.Pars {
+		r.Pars = append(r.Pars, Par{Tee: rename(tees, p.Tee), Pin: rename(pins, p.Pin), Par: p.Par})
+	}
+	r.Fairways = nil
+	for _, f := range h.Fairways {
+		r.Fairways = append(r.Fairways, Fairway{Tee: rename(tees, f.Tee), Pin: rename(pins, f.Pin), Path: f.Path})
+	}
+	return r
+}
+
+// Course b with its holes, tees and pins renamed to the IDs of whatever
+// they match in a, along with the renames
+func alignCourse(a Course, b Course) (Course, []CourseChange) {
+	var cc []CourseChange
+
+	var aIDs, bIDs []string
+	var aLocs, bLocs []Loc
+	for _, h := range a.Holes {
+		aIDs = append(aIDs, h.ID)
+		aLocs = append(aLocs, h.center())
+	}
+	for _, h := range b.Holes {
+		bIDs = append(bIDs, h.ID)
+		bLocs = append(bLocs, h.center())
+	}
+	holes := matchIDs(aIDs, aLocs, bIDs, bLocs, HoleMatchDist)
+
+	r := b
+	r.Holes = nil
+	teeMaps := make(map[string]map[string]string)
+	pinMaps := make(map[string]map[string]string)
+	for _, hb := range b.Holes {
+		hID := rename(holes, hb.ID)
+		if hID != hb.ID {
+			cc = append(cc, CourseChange{Hole: hID, Kind: "hole", ID: hID, Change: "renamed", From: hID, To: hb.ID, Dist: Dist(a.GetHole(hID).center(), hb.center())})
+		}
+
+		ha := a.GetHole(hID)
+		var ta, tb, pa, pb []string
+		var tal, tbl, pal, pbl []Loc
+		for _, t := range ha.Tees {
+			ta, tal = append(ta, t.ID), append(tal, t.Loc)
+		}
+		for _, t := range hb.Tees {
+			tb, tbl = append(tb, t.ID), append(tbl, t.Loc)
+		}
+		for _, p := range ha.Pins {
+			pa, pal = append(pa, p.ID), append(pal, p.Loc)
+		}
+		for _, p := range hb.Pins {
+			pb, pbl = append(pb, p.ID), append(pbl, p.Loc)
+		}
+		tees := matchIDs(ta, tal, tb, tbl, PointMatchDist)
+		pins := matchIDs(pa, pal, pb, pbl, PointMatchDist)
+		teeMaps[hb.ID] = tees
+		pinMaps[hb.ID] = pins
+
+		for _, t := range hb.Tees {
+			if n := rename(tees, t.ID); n != t.ID {
+				cc = append(cc, CourseChange{Hole: hID, Kind: "tee", ID: n, Change: "renamed", From: n, To: t.ID, Dist: Dist(ha.GetTee(n).Loc, t.Loc)})
+			}
+		}
+		for _, p := range hb.Pins {
+			if n := rename(pins, p.ID); n != p.ID {
+				cc = append(cc, CourseChange{Hole: hID, Kind: "pin", ID: n, Change: "renamed", From: n, To: p.ID, Dist: Dist(ha.GetPin(n).Loc, p.Loc)})
+			}
+		}
+
+		r.Holes = append(r.Holes, hb.renamed(hID, tees, pins))
+	}
+
+	r.Sequence = nil
+	for _, id := range b.Sequence {
+		r.Sequence = append(r.Sequence, rename(holes, id))
+	}
+	r.Layouts = nil
+	for _, l := range b.Layouts {
+		rl := l
+		rl.Holes = nil
+		for _, lh := range l.Holes {
+			rl.Holes = append(rl.Holes, LayoutHole{
+				Hole: rename(holes, lh.Hole),
+				Tee:  rename(teeMaps[lh.Hole], lh.Tee),
+				Pin:  rename(pinMaps[lh.Hole], lh.Pin),
+			})
+		}
+		r.Layouts = append(r.Layouts, rl)
+	}
+	r.Rotations = nil
+	for _, pr := range b.Rotations {
+		rr := pr
+		rr.Pins = map[string]string{}
+		for hID, pID := range pr.Pins {
+			rr.Pins[rename(holes, hID)] = rename(pinMaps[hID], pID)
+		}
+		r.Rotations = append(r.Rotations, rr)
+	}
+
+	return r, cc
+}
+
+// Changes needed to turn course a into course b. Holes, tees and pins are
+// matched by ID, or failing that by distance.
+func DiffCourses(a Course, b Course) []CourseChange {
+	b, cc := alignCourse(a, b)
+
+	for _, ha := range a.Holes {
+		hb := b.GetHole(ha.ID)
+		if hb.ID == "" {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "hole", Change: "removed"})
+			continue
+		}
+		cc = append(cc, diffHoles(ha, hb)...)
+	}
+	for _, hb := range b.Holes {
+		if a.GetHole(hb.ID).ID == "" {
+			cc = append(cc, CourseChange{Hole: hb.ID, Kind: "hole", Change: "added"})
+		}
+	}
+
+	if !reflect.DeepEqual(a.Rotations, b.Rotations) {
+		cc = append(cc, CourseChange{Hole: "all", Kind: "pin rotation", Change: "changed",
+			From: fmt.Sprintf("%d rotations", len(a.Rotations)), To: fmt.Sprintf("%d rotations", len(b.Rotations))})
+	}
+
+	return cc
+}
+
+func diffHoles(ha Hole, hb Hole) []CourseChange {
+	var cc []CourseChange
+
+	for _, ta := range ha.Tees {
+		tb := hb.GetTee(ta.ID)
+		if tb.ID == "" {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "tee", ID: ta.ID, Change: "removed"})
+		} else if d := Dist(ta.Loc, tb.Loc); d > moveThresh {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "tee", ID: ta.ID, Change: "moved", Dist: d})
+		}
+	}
+	for _, tb := range hb.Tees {
+		if ha.GetTee(tb.ID).ID == "" {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "tee", ID: tb.ID, Change: "added"})
+		}
+	}
+
+	for _, pa := range ha.Pins {
+		pb := hb.GetPin(pa.ID)
+		if pb.ID == "" {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "pin", ID: pa.ID, Change: "removed"})
+		} else if d := Dist(pa.Loc, pb.Loc); d > moveThresh {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "pin", ID: pa.ID, Change: "moved", Dist: d})
+		}
+	}
+	for _, pb := range hb.Pins {
+		if ha.GetPin(pb.ID).ID == "" {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "pin", ID: pb.ID, Change: "added"})
+		}
+	}
+
+	for _, t := range hb.Tees {
+		for _, p := range hb.Pins {
+			if ha.GetTee(t.ID).ID == "" || ha.GetPin(p.ID).ID == "" {
+				continue
+			}
+			pa := ha.Par(t.ID, p.ID)
+			pb := hb.Par(t.ID, p.ID)
+			if pa != pb {
+				cc = append(cc, CourseChange{Hole: ha.ID, Kind: "par", ID: t.ID + "->" + p.ID, Change: "changed", From: strconv.Itoa(pa), To: strconv.Itoa(pb)})
+			}
+			if !sameLocs(ha.Waypoints(t.ID, p.ID), hb.Waypoints(t.ID, p.ID)) {
+				cc = append(cc, CourseChange{Hole: ha.ID, Kind: "fairway", ID: t.ID + "->" + p.ID, Change: "changed",
+					From: strconv.Itoa(len(ha.Waypoints(t.ID, p.ID))) + " waypoints", To: strconv.Itoa(len(hb.Waypoints(t.ID, p.ID))) + " waypoints"})
+			}
+		}
+	}
+
+	for _, za := range ha.Hazards {
+		found := false
+		for _, zb := range hb.Hazards {
+			if za.ID == zb.ID {
+				found = true
+				if za.Type != zb.Type || !sameLocs(za.Poly, zb.Poly) {
+					cc = append(cc, CourseChange{Hole: ha.ID, Kind: "hazard", ID: za.ID, Change: "changed", From: za.Type, To: zb.Type})
+				}
+			}
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "hazard", ID: za.ID, Change: "removed"})
+		}
+	}
+	for _, zb := range hb.Hazards {
+		found := false
+		for _, za := range ha.Hazards {
+			found = found || za.ID == zb.ID
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "hazard", ID: zb.ID, Change: "added"})
+		}
+	}
+
+	for _, ma := range ha.Mandos {
+		found := false
+		for _, mb := range hb.Mandos {
+			if ma.ID == mb.ID {
+				found = true
+				if ma.Side != mb.Side || !sameLocs(ma.Line, mb.Line) {
+					cc = append(cc, CourseChange{Hole: ha.ID, Kind: "mando", ID: ma.ID, Change: "changed", From: ma.Side, To: mb.Side})
+				}
+			}
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "mando", ID: ma.ID, Change: "removed"})
+		}
+	}
+	for _, mb := range hb.Mandos {
+		found := false
+		for _, ma := range ha.Mandos {
+			found = found || ma.ID == mb.ID
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "mando", ID: mb.ID, Change: "added"})
+		}
+	}
+
+	for _, da := range ha.DropZones {
+		found := false
+		for _, db := range hb.DropZones {
+			if da.ID == db.ID {
+				found = true
+				if d := Dist(da.Loc, db.Loc); d > moveThresh {
+					cc = append(cc, CourseChange{Hole: ha.ID, Kind: "drop zone", ID: da.ID, Change: "moved", Dist: d})
+				}
+			}
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "drop zone", ID: da.ID, Change: "removed"})
+		}
+	}
+	for _, db := range hb.DropZones {
+		found := false
+		for _, da := range ha.DropZones {
+			found = found || da.ID == db.ID
+		}
+		if !found {
+			cc = append(cc, CourseChange{Hole: ha.ID, Kind: "drop zone", ID: db.ID, Change: "added"})
+		}
+	}
+
+	return cc
+}
@@ -0,0 +1,84 @@
+package rnd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Maps the names a recording app gives waypoints to the names of the discs
+// in discs.csv. Keys are normalized, an empty disc means the waypoint isn't
+// a throw and is dropped.
+type DiscAliases map[string]string
+
+// Upper case with runs of spaces, dashes and underscores made one
+// underscore, the way disc names are written
+func normDiscName(s string) string {
+	f := strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	})
+	return strings.Join(f, "_")
+}
+
+// Reads discs/aliases.csv, "alias,disc" lines under a header. Every disc is
+// also its own alias, by name and by catalog ID.
+func GetDiscAliases() DiscAliases {
+	da := DiscAliases{}
+	for _, d := range GetDiscs() {
+		da[normDiscName(d.Name)] = d.Name
+		if d.ID != "" {
+			da[normDiscName(d.ID)] = d.Name
+		}
+	}
+
+	b, err := DefaultStore().ReadDiscAliases()
+	if errors.Is(err, ErrNotFound) {
+		return da
+	}
+	if err != nil {
+		fmt.Println("disc aliases: " + err.Error())
+		return da
+	}
+
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	lines, err := r.ReadAll()
+	if err != nil {
+		fmt.Println("disc aliases: " + err.Error())
+		return da
+	}
+	for i, l := range lines {
+		if i == 0 || len(l) == 0 || l[0] == "" {
+			continue // header
+		}
+		disc := ""
+		if len(l) > 1 {
+			disc = strings.TrimSpace(l[1])
+		}
+		da[normDiscName(l[0])] = disc
+	}
+	return da
+}
+
+// The disc a waypoint name stands for, false if the waypoint should be
+// dropped. Names with no alias are taken as disc names, as they are.
+func (da DiscAliases) Disc(name string) (string, bool) {
+	n := normDiscName(name)
+	if n == "" {
+		return "", false
+	}
+	disc, ok := da[n]
+	if !ok {
+		return name, true
+	}
+	return disc, disc != ""
+}
+
+// Whether a waypoint name has neither an alias nor a disc of its own
+func (da DiscAliases) Unknown(name string) bool {
+	n := normDiscName(name)
+	_, ok := da[n]
+	return n != "" && !ok
+}
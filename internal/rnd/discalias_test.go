@@ -0,0 +1,33 @@
+package rnd
+
+import "testing"
+
+func TestDiscAliases(t *testing.T) {
+	da := DiscAliases{"ZONE": "Zone", "Z": "Zone", "PARKED": ""}
+
+	tests := []struct {
+		name    string
+		disc    string
+		ok      bool
+		unknown bool
+	}{
+		{"zone", "Zone", true, false},
+		{" z ", "Zone", true, false},
+		{"parked", "", false, false},
+		{"", "", false, false},
+		// no alias, taken as named
+		{"Big Z Buzzz", "Big Z Buzzz", true, true},
+	}
+	for _, tt := range tests {
+		disc, ok := da.Disc(tt.name)
+		if disc != tt.disc || ok != tt.ok {
+			t.Errorf("%q: disc %q %v, want %q %v", tt.name, disc, ok, tt.disc, tt.ok)
+		}
+		if u := da.Unknown(tt.name); u != tt.unknown {
+			t.Errorf("%q: unknown %v, want %v", tt.name, u, tt.unknown)
+		}
+	}
+	if len(da) != 3 {
+		t.Errorf("looking up discs left %d aliases, want 3", len(da))
+	}
+}
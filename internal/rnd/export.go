@@ -0,0 +1,165 @@
+package rnd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// Names that import-course reads back, e.g. "5 tee reg"
+func placemarkName(hID string, kind string, id string) string {
+	return hID + " " + kind + " " + id
+}
+
+func gpxPoint(l Loc, name string, kind string) gpx.GPXPoint {
+	p := gpx.GPXPoint{
+		Point: gpx.Point{
+			Latitude:  l[0],
+			Longitude: l[1],
+		},
+		Name: name,
+		Type: kind,
+	}
+	if l.HasAlt() {
+		p.Elevation.SetValue(l.Alt())
+	}
+	return p
+}
+
+// One waypoint per tee, pin and drop zone and a route along the fairway for
+// each tee and pin pair
+func (c Course) GPX() ([]byte, error) {
+	g := gpx.GPX{
+		Version: "1.1",
+		Creator: "dg_record",
+		Name:    c.Name,
+	}
+
+	for _, h := range c.Holes {
+		for _, t := range h.Tees {
+			g.Waypoints = append(g.Waypoints, gpxPoint(t.Loc, placemarkName(h.ID, "tee", t.ID), "tee"))
+		}
+		for _, p := range h.Pins {
+			g.Waypoints = append(g.Waypoints, gpxPoint(p.Loc, placemarkName(h.ID, "pin", p.ID), "pin"))
+		}
+		for _, d := range h.DropZones {
+			g.Waypoints = append(g.Waypoints, gpxPoint(d.Loc, placemarkName(h.ID, "drop", d.ID), "drop"))
+		}
+
+		for _, t := range h.Tees {
+			for _, p := range h.Pins {
+				name := h.ID
+				if len(h.Tees) > 1 || len(h.Pins) > 1 {
+					name = h.ID + " " + t.ID + "->" + p.ID
+				}
+				rte := gpx.GPXRoute{
+					Name:        name,
+					Description: fmt.Sprintf("Par %d", h.Par(t.ID, p.ID)),
+				}
+				for i, l := range h.Path(t.ID, p.ID) {
+					rte.Points = append(rte.Points, gpxPoint(l, fmt.Sprintf("%s %d", name, i), ""))
+				}
+				g.Routes = append(g.Routes, rte)
+			}
+		}
+	}
+
+	return g.ToXml(gpx.ToXmlParams{Version: "1.1", Indent: true})
+}
+
+func (c Course) WriteGPX() {
+	b, err := c.GPX()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := DefaultStore().WriteExport(c.ID+".gpx", b); err != nil {
+		fmt.Println(err)
+	}
+}
+
+type kmlOut struct {
+	XMLName  xml.Name    `xml:"kml"`
+	NS       string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name    string      `xml:"name"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+func kmlCoords(path []Loc) string {
+	var s []string
+	for _, l := range path {
+		if l.HasAlt() {
+			s = append(s, fmt.Sprintf("%f,%f,%f", l[1], l[0], l.Alt()))
+		} else {
+			s = append(s, fmt.Sprintf("%f,%f", l[1], l[0]))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func pointPlacemark(l Loc, name string) kmlPlacemark {
+	return kmlPlacemark{
+		Name:  name,
+		Point: &kmlCoordinates{kmlCoords([]Loc{l})},
+	}
+}
+
+// A folder per hole with its tees, pins and fairway lines
+func (c Course) KML() ([]byte, error) {
+	k := kmlOut{
+		NS: "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{
+			Name: c.Name,
+		},
+	}
+
+	for _, h := range c.Holes {
+		f := kmlFolder{Name: "Hole " + h.ID}
+		for _, t := range h.Tees {
+			f.Placemarks = append(f.Placemarks, pointPlacemark(t.Loc, placemarkName(h.ID, "tee", t.ID)))
+		}
+		for _, p := range h.Pins {
+			f.Placemarks = append(f.Placemarks, pointPlacemark(p.Loc, placemarkName(h.ID, "pin", p.ID)))
+		}
+		for _, d := range h.DropZones {
+			f.Placemarks = append(f.Placemarks, pointPlacemark(d.Loc, placemarkName(h.ID, "drop", d.ID)))
+		}
+		for _, t := range h.Tees {
+			for _, p := range h.Pins {
+				f.Placemarks = append(f.Placemarks, kmlPlacemark{
+					Name:       fmt.Sprintf("%s %s->%s par %d", h.ID, t.ID, p.ID, h.Par(t.ID, p.ID)),
+					LineString: &kmlCoordinates{kmlCoords(h.Path(t.ID, p.ID))},
+				})
+			}
+		}
+		k.Document.Folders = append(k.Document.Folders, f)
+	}
+
+	b, err := xml.MarshalIndent(k, "", "	")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func (c Course) WriteKML() {
+	b, err := c.KML()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := DefaultStore().WriteExport(c.ID+".kml", b); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,314 @@
+package rnd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Placemark struct {
+	Name string
+	Loc  Loc
+}
+
+// Patterns turn placemark names like "5 tee reg" or "pin 5 x" into holes, tees
+// and pins. Each pattern needs hole and kind groups and may have an id group,
+// the kind is matched against TeeWords, PinWords and DropWords
+type NamePatterns struct {
+	Patterns  []string
+	TeeWords  []string
+	PinWords  []string
+	DropWords []string
+	TeeID     string
+	PinID     string
+}
+
+var DefaultNamePatterns = NamePatterns{
+	Patterns: []string{
+		`^(?P<hole>\d+[a-z]?)[\s_-]+(?P<kind>[a-z]+)(?:[\s_-]+(?P<id>\w+))?$`,
+		`^(?P<kind>[a-z]+)[\s_-]*(?P<hole>\d+[a-z]?)(?:[\s_-]+(?P<id>\w+))?$`,
+	},
+	TeeWords:  []string{"tee", "t", "pad", "teepad"},
+	PinWords:  []string{"pin", "p", "basket", "b", "target"},
+	DropWords: []string{"drop", "dz", "dropzone"},
+	TeeID:     "reg",
+	PinID:     "x",
+}
+
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPlacemark struct {
+	Name       string          `xml:"name"`
+	Point      *kmlCoordinates `xml:"Point,omitempty"`
+	LineString *kmlCoordinates `xml:"LineString,omitempty"`
+}
+
+func parseKMLCoords(s string) Loc {
+	f := strings.Split(strings.TrimSpace(s), ",")
+	if len(f) < 2 {
+		return nil
+	}
+	lon, _ := strconv.ParseFloat(strings.TrimSpace(f[0]), 64)
+	lat, _ := strconv.ParseFloat(strings.TrimSpace(f[1]), 64)
+	l := Loc{lat, lon}
+	if len(f) > 2 {
+		alt, _ := strconv.ParseFloat(strings.TrimSpace(f[2]), 64)
+		if alt != 0 {
+			l = append(l, alt)
+		}
+	}
+	return l
+}
+
+func readKML(r io.Reader) []Placemark {
+	var pms []Placemark
+
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "Placemark" {
+			continue
+		}
+
+		var pm kmlPlacemark
+		d.DecodeElement(&pm, &se)
+		if pm.Point == nil {
+			continue
+		}
+		if l := parseKMLCoords(pm.Point.Coordinates); l != nil {
+			pms = append(pms, Placemark{strings.TrimSpace(pm.Name), l})
+		}
+	}
+
+	return pms
+}
+
+// Point placemarks from a Google Earth / My Maps .kml or .kmz file
+func ParseKML(filename string) []Placemark {
+	if strings.ToLower(path.Ext(filename)) != ".kmz" {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Println(err)
+			return nil
+		}
+		defer f.Close()
+		return readKML(f)
+	}
+
+	z, err := zip.OpenReader(filename)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	defer z.Close()
+
+	var pms []Placemark
+	for _, zf := range z.File {
+		if strings.ToLower(path.Ext(zf.Name)) != ".kml" {
+			continue
+		}
+		f, err := zf.Open()
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		pms = append(pms, readKML(f)...)
+		f.Close()
+	}
+	return pms
+}
+
+type geoJSONPoints struct {
+	Features []struct {
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// Point features from a GeoJSON FeatureCollection, named by their name or
+// title property
+func ParseGeoJSONPoints(filename string) []Placemark {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	var gj geoJSONPoints
+	if err := json.Unmarshal(b, &gj); err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	var pms []Placemark
+	for _, f := range gj.Features {
+		c := f.Geometry.Coordinates
+		if f.Geometry.Type != "Point" || len(c) < 2 {
+			continue
+		}
+
+		name := ""
+		for _, k := range []string{"name", "Name", "title", "label"} {
+			if s, ok := f.Properties[k].(string); ok {
+				name = s
+				break
+			}
+		}
+
+		l := Loc{c[1], c[0]}
+		if len(c) > 2 && c[2] != 0 {
+			l = append(l, c[2])
+		}
+		pms = append(pms, Placemark{strings.TrimSpace(name), l})
+	}
+	return pms
+}
+
+func ParsePlacemarks(filename string) []Placemark {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".kml", ".kmz":
+		return ParseKML(filename)
+	default:
+		return ParseGeoJSONPoints(filename)
+	}
+}
+
+func containsWord(words []string, w string) bool {
+	for _, x := range words {
+		if strings.EqualFold(x, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// The patterns compiled, case insensitive. Bad ones are reported and left
+// out.
+func (np NamePatterns) compile() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, p := range np.Patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// Splits a placemark name into its hole, kind ("tee", "pin" or "drop") and
+// id using the compiled patterns res
+func (np NamePatterns) match(res []*regexp.Regexp, name string) (string, string, string, bool) {
+	for _, re := range res {
+		m := re.FindStringSubmatch(strings.TrimSpace(name))
+		if m == nil {
+			continue
+		}
+
+		hole, kind, id := "", "", ""
+		for i, g := range re.SubexpNames() {
+			switch g {
+			case "hole":
+				hole = m[i]
+			case "kind":
+				kind = m[i]
+			case "id":
+				id = m[i]
+			}
+		}
+
+		if hole == "" {
+			continue
+		}
+		if containsWord(np.TeeWords, kind) {
+			if id == "" {
+				id = np.TeeID
+			}
+			return hole, "tee", id, true
+		}
+		if containsWord(np.PinWords, kind) {
+			if id == "" {
+				id = np.PinID
+			}
+			return hole, "pin", id, true
+		}
+		if containsWord(np.DropWords, kind) {
+			if id == "" {
+				id = "dz"
+			}
+			return hole, "drop", id, true
+		}
+	}
+	return "", "", "", false
+}
+
+// Builds a course from named placemarks, holes are kept in the order they
+// first appear. Names that match no pattern are returned
+func PlacemarksToCourse(id string, name string, pms []Placemark, np NamePatterns) (Course, []string) {
+	var holes []Hole
+	var unmatched []string
+
+	res := np.compile()
+	hole_idx := make(map[string]int)
+	for _, pm := range pms {
+		hID, kind, pID, ok := np.match(res, pm.Name)
+		if !ok {
+			unmatched = append(unmatched, pm.Name)
+			continue
+		}
+
+		i, found := hole_idx[hID]
+		if !found {
+			i = len(holes)
+			hole_idx[hID] = i
+			holes = append(holes, Hole{ID: hID})
+		}
+
+		switch kind {
+		case "tee":
+			holes[i].Tees = append(holes[i].Tees, Tee{ID: pID, Loc: pm.Loc})
+		case "pin":
+			holes[i].Pins = append(holes[i].Pins, Pin{ID: pID, Loc: pm.Loc})
+		case "drop":
+			holes[i].DropZones = append(holes[i].DropZones, DropZone{ID: pID, Loc: pm.Loc})
+		}
+	}
+
+	c := Course{
+		ID:    id,
+		Name:  name,
+		Holes: holes,
+	}
+	for _, h := range holes {
+		if len(h.Tees) > 0 {
+			c.Loc = h.Tees[0].Loc
+			break
+		}
+	}
+	c.SuggestPars(false)
+
+	return c, unmatched
+}
@@ -0,0 +1,26 @@
+package rnd
+
+import "testing"
+
+func TestPlacemarksToCoursePars(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemStore())
+
+	pms := []Placemark{
+		{Name: "1 tee", Loc: at(0, 0)},
+		{Name: "1 pin", Loc: at(0, 80)},
+		{Name: "2 tee", Loc: at(20, 80)},
+		{Name: "2 pin", Loc: at(20, 250)},
+	}
+	c, unmatched := PlacemarksToCourse("imp", "Imported", pms, DefaultNamePatterns)
+	if len(unmatched) > 0 {
+		t.Fatalf("unmatched %v", unmatched)
+	}
+
+	want := map[string]int{"1": 3, "2": 4}
+	for hID, par := range want {
+		if p := c.GetHole(hID).Par("reg", "x"); p != par {
+			t.Errorf("hole %s par %d, want %d from its length", hID, p, par)
+		}
+	}
+}
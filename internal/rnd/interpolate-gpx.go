@@ -6,8 +6,13 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jacobwood27/go-dg-record/internal/fit"
 	"github.com/sgreben/piecewiselinear"
 	"github.com/tkrajina/gpxgo/gpx"
 )
@@ -45,24 +50,132 @@ func parseTimestampFile(filename string) timestamps {
 }
 
 type locstamp struct {
-	time time.Time
-	lat  float64
-	lon  float64
+	time   time.Time
+	lat    float64
+	lon    float64
+	alt    float64
+	hasAlt bool
+	acc    float64 // horizontal error in meters, 0 if unknown
+}
+
+// Accuracy in meters a recording app put in a point's extensions
+func extensionAccuracy(nodes []gpx.ExtensionNode) float64 {
+	for _, n := range nodes {
+		switch strings.ToLower(n.LocalName()) {
+		case "accuracy", "hacc", "horizontal_accuracy", "horizontalaccuracy":
+			if a, err := strconv.ParseFloat(strings.TrimSpace(n.Data), 64); err == nil && a > 0 {
+				return a
+			}
+		}
+		if a := extensionAccuracy(n.Nodes); a > 0 {
+			return a
+		}
+	}
+	return 0
+}
+
+// Horizontal error of a GPX point, from its accuracy if the app wrote one
+// and its HDOP otherwise
+func gpxAccuracy(p gpx.GPXPoint) float64 {
+	if a := extensionAccuracy(p.Extensions.Nodes); a > 0 {
+		return a
+	}
+	if p.HorizontalDilution.NotNull() {
+		return p.HorizontalDilution.Value() * UERE
+	}
+	return 0
 }
+
 type locstamps []locstamp
 
-func parseGPXFile(file string) locstamps {
-	t, _ := gpx.ParseFile(file)
+// Reads the track of a GPX file, and any timed waypoints as timestamps
+// labeled with the waypoint names
+func parseGPXFile(file string) (locstamps, timestamps) {
+	t, err := gpx.ParseFile(file)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	var df []locstamp
 	for _, track := range t.Tracks {
 		for _, segment := range track.Segments {
 			for _, point := range segment.Points {
-				df = append(df, locstamp{point.Timestamp, point.Latitude, point.Longitude})
+				df = append(df, locstamp{
+					time:   point.Timestamp,
+					lat:    point.Latitude,
+					lon:    point.Longitude,
+					alt:    point.Elevation.Value(),
+					hasAlt: point.Elevation.NotNull(),
+					acc:    gpxAccuracy(point),
+				})
 			}
 		}
 	}
-	return locstamps(df)
+
+	// with no track the waypoints are all there is to go on
+	noTrack := len(df) == 0
+
+	var ts []timestamp
+	for _, w := range t.Waypoints {
+		if w.Timestamp.IsZero() {
+			continue
+		}
+		ts = append(ts, timestamp{w.Timestamp, w.Name})
+
+		if noTrack {
+			df = append(df, locstamp{
+				time:   w.Timestamp,
+				lat:    w.Latitude,
+				lon:    w.Longitude,
+				alt:    w.Elevation.Value(),
+				hasAlt: w.Elevation.NotNull(),
+				acc:    gpxAccuracy(w),
+			})
+		}
+	}
+	sort.SliceStable(ts, func(i, j int) bool { return ts[i].time.Before(ts[j].time) })
+	sort.SliceStable(df, func(i, j int) bool { return df[i].time.Before(df[j].time) })
+
+	return locstamps(df), timestamps(ts)
+}
+
+// Reads the track of a FIT file, and its lap button presses and markers as
+// timestamps with no disc
+func parseFITFile(file string) (locstamps, timestamps) {
+	act, err := fit.ReadFile(file)
+	if err != nil {
+		log.Fatal(file + ": " + err.Error())
+	}
+
+	var df []locstamp
+	for _, r := range act.Records {
+		if !r.HasPos {
+			continue
+		}
+		df = append(df, locstamp{
+			time:   r.Time,
+			lat:    r.Lat,
+			lon:    r.Lon,
+			alt:    r.Alt,
+			hasAlt: r.HasAlt,
+			acc:    r.Accuracy,
+		})
+	}
+
+	var ts []timestamp
+	for _, m := range act.Markers {
+		ts = append(ts, timestamp{m.Time, "UNDEFINED"})
+	}
+
+	return locstamps(df), timestamps(ts)
+}
+
+// A GPX or FIT recording, by its extension
+func parseRecording(file string) (locstamps, timestamps) {
+	if strings.EqualFold(filepath.Ext(file), ".fit") {
+		return parseFITFile(file)
+	}
+	return parseGPXFile(file)
 }
 
 func interpolateGPX(ts timestamps, locs locstamps) Stamps {
@@ -76,19 +189,31 @@ func interpolateGPX(ts timestamps, locs locstamps) Stamps {
 		X: make([]float64, 0),
 		Y: make([]float64, 0),
 	}
+	f_alt := piecewiselinear.Function{
+		X: make([]float64, 0),
+		Y: make([]float64, 0),
+	}
 	for _, l := range locs {
 		f_lat.X = append(f_lat.X, float64(l.time.UnixNano()))
 		f_lat.Y = append(f_lat.Y, l.lat)
 		f_lon.X = append(f_lon.X, float64(l.time.UnixNano()))
 		f_lon.Y = append(f_lon.Y, l.lon)
+		if l.hasAlt {
+			f_alt.X = append(f_alt.X, float64(l.time.UnixNano()))
+			f_alt.Y = append(f_alt.Y, l.alt)
+		}
 	}
 
 	for _, t := range ts {
+		loc := Loc{
+			f_lat.At(float64(t.time.UnixNano())),
+			f_lon.At(float64(t.time.UnixNano())),
+		}
+		if len(f_alt.X) > 0 {
+			loc = append(loc, f_alt.At(float64(t.time.UnixNano())))
+		}
 		tls = append(tls, Stamp{
-			Loc: Loc{
-				f_lat.At(float64(t.time.UnixNano())),
-				f_lon.At(float64(t.time.UnixNano())),
-			},
+			Loc:  loc,
 			Disc: t.disc,
 		})
 	}
@@ -110,9 +235,14 @@ func interpolateGPX(ts timestamps, locs locstamps) Stamps {
 func GetRoundRaw(ts_csv string, rec_gpx string) (Stamps, string) {
 	ts := parseTimestampFile(ts_csv)
 
-	gpx := parseGPXFile(rec_gpx)
+	gpx, _ := parseRecording(rec_gpx)
+	gpx = smoothTrack(gpx)
 
-	igpx := interpolateGPX(ts, gpx)
+	// the phone logging the throws and the recorder may not agree on the time
+	off, msg := chooseClockOffset(ClockOffsetSetting(), ts, gpx, trackAnchors(gpx))
+	fmt.Println(msg)
+
+	igpx := interpolateGPX(ts.shift(off), gpx)
 
 	fID := ts[0].time.Format("2006-01-02-15-04-05")
 	// igpx.WriteRoundRawCSV(fID)
@@ -120,3 +250,47 @@ func GetRoundRaw(ts_csv string, rec_gpx string) (Stamps, string) {
 	return igpx, fID
 
 }
+
+// Like GetRoundRaw, with the throws taken from the waypoints of the GPX file
+// and their names turned into discs through the alias table
+func GetRoundGPX(rec_gpx string) (Stamps, string) {
+	gpx, wpts := parseGPXFile(rec_gpx)
+
+	da := GetDiscAliases()
+	unknown := map[string]bool{}
+	var ts timestamps
+	for _, w := range wpts {
+		if da.Unknown(w.disc) && !unknown[w.disc] {
+			unknown[w.disc] = true
+			fmt.Println("no disc for waypoint " + w.disc + ", kept as named")
+		}
+		if disc, ok := da.Disc(w.disc); ok {
+			ts = append(ts, timestamp{w.time, disc})
+		}
+	}
+	if len(ts) == 0 {
+		log.Fatal(rec_gpx + ": no timed waypoints")
+	}
+
+	igpx := interpolateGPX(ts, smoothTrack(gpx))
+
+	// GPX times are UTC, round IDs are local
+	fID := ts[0].time.Local().Format("2006-01-02-15-04-05")
+
+	return igpx, fID
+}
+
+// Like GetRoundRaw for a FIT file without a ts.csv, a throw for every press
+// of the lap button. The discs are left for edit-round.
+func GetRoundFIT(rec_fit string) (Stamps, string) {
+	locs, ts := parseFITFile(rec_fit)
+	if len(ts) == 0 {
+		log.Fatal(rec_fit + ": no laps or markers")
+	}
+
+	igpx := interpolateGPX(ts, smoothTrack(locs))
+
+	fID := ts[0].time.Local().Format("2006-01-02-15-04-05")
+
+	return igpx, fID
+}
@@ -0,0 +1,294 @@
+package rnd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tees and pins two surveys put closer than this agree, and are averaged
+// when merged
+var MergeAgreeDist = 3.0
+
+// Something two surveys of a course disagree on. The merge keeps our
+// version and theirs is kept here until it's resolved in edit-course.
+type MergeConflict struct {
+	Hole   string  `json:"hole"`
+	Kind   string  `json:"kind"`
+	ID     string  `json:"id"`
+	Dist   float64 `json:"dist,omitempty"`
+	Ours   string  `json:"ours"`
+	Theirs string  `json:"theirs"`
+
+	// their tee or pin, their fairway, hazard or mando line, their par
+	Loc  Loc   `json:"loc,omitempty"`
+	Path []Loc `json:"path,omitempty"`
+	Par  int   `json:"par,omitempty"`
+}
+
+func (mc MergeConflict) String() string {
+	s := "hole " + mc.Hole + ": " + mc.Kind + " " + mc.ID
+	switch mc.Kind {
+	case "tee", "pin", "drop zone":
+		s += fmt.Sprintf(" %.1f m apart", mc.Dist)
+	default:
+		s += " " + mc.Ours + " vs " + mc.Theirs
+	}
+	return s
+}
+
+func (h Hole) copy() Hole {
+	r := h
+	r.Tees = append([]Tee{}, h.Tees...)
+	r.Pins = append([]Pin{}, h.Pins...)
+	r.Pars = append([]Par{}, h.Pars...)
+	r.Fairways = append([]Fairway{}, h.Fairways...)
+	r.Hazards = append([]Hazard{}, h.Hazards...)
+	r.Mandos = append([]Mando{}, h.Mandos...)
+	r.DropZones = append([]DropZone{}, h.DropZones...)
+	return r
+}
+
+func midLoc(a Loc, b Loc) Loc {
+	m := Loc{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2}
+	switch {
+	case a.HasAlt() && b.HasAlt():
+		m = append(m, (a.Alt()+b.Alt())/2)
+	case a.HasAlt():
+		m = append(m, a.Alt())
+	case b.HasAlt():
+		m = append(m, b.Alt())
+	}
+	return m
+}
+
+func (h Hole) hasPar(tID string, pID string) bool {
+	for _, p := range h.Pars {
+		if p.Tee == tID && p.Pin == pID {
+			return true
+		}
+	}
+	return false
+}
+
+// Merges their survey of a course into ours. Holes, tees and pins are
+// matched as DiffCourses does. Anything only one survey has is kept, tees
+// and pins that agree are averaged, and where they disagree ours is kept and
+// theirs recorded in Conflicts.
+func MergeCourses(ours Course, theirs Course) Course {
+	m := ours
+	m.Holes = nil
+	for _, h := range ours.Holes {
+		m.Holes = append(m.Holes, h.copy())
+	}
+	m.Conflicts = append([]MergeConflict{}, ours.Conflicts...)
+
+	t, _ := alignCourse(ours, theirs)
+	for _, ht := range t.Holes {
+		i := -1
+		for j, h := range m.Holes {
+			if h.ID == ht.ID {
+				i = j
+			}
+		}
+		if i < 0 {
+			m.Holes = append(m.Holes, ht.copy())
+			continue
+		}
+
+		m.Conflicts = append(m.Conflicts, mergeHole(&m.Holes[i], ht)...)
+	}
+
+	for _, l := range t.Layouts {
+		if m.GetLayout(l.ID).ID == "" {
+			m.Layouts = append(m.Layouts, l)
+		}
+	}
+	if len(m.Sequence) == 0 {
+		m.Sequence = t.Sequence
+	}
+	if m.MandoRule == "" {
+		m.MandoRule = t.MandoRule
+	}
+	if len(m.Rotations) == 0 {
+		m.Rotations = t.Rotations
+	}
+
+	return m
+}
+
+func mergeHole(h *Hole, ht Hole) []MergeConflict {
+	var mc []MergeConflict
+
+	for _, tt := range ht.Tees {
+		found := false
+		for k, to := range h.Tees {
+			if to.ID != tt.ID {
+				continue
+			}
+			found = true
+			if d := Dist(to.Loc, tt.Loc); d <= MergeAgreeDist {
+				h.Tees[k].Loc = midLoc(to.Loc, tt.Loc)
+			} else {
+				mc = append(mc, MergeConflict{Hole: h.ID, Kind: "tee", ID: tt.ID, Dist: d, Loc: tt.Loc})
+			}
+		}
+		if !found {
+			h.Tees = append(h.Tees, tt)
+		}
+	}
+
+	for _, pt := range ht.Pins {
+		found := false
+		for k, po := range h.Pins {
+			if po.ID != pt.ID {
+				continue
+			}
+			found = true
+			if d := Dist(po.Loc, pt.Loc); d <= MergeAgreeDist {
+				h.Pins[k].Loc = midLoc(po.Loc, pt.Loc)
+			} else {
+				mc = append(mc, MergeConflict{Hole: h.ID, Kind: "pin", ID: pt.ID, Dist: d, Loc: pt.Loc})
+			}
+		}
+		if !found {
+			h.Pins = append(h.Pins, pt)
+		}
+	}
+
+	for _, p := range ht.Pars {
+		if !h.hasPar(p.Tee, p.Pin) {
+			h.Pars = append(h.Pars, p)
+		} else if o := h.Par(p.Tee, p.Pin); o != p.Par {
+			mc = append(mc, MergeConflict{Hole: h.ID, Kind: "par", ID: p.Tee + "->" + p.Pin,
+				Ours: fmt.Sprint(o), Theirs: fmt.Sprint(p.Par), Par: p.Par})
+		}
+	}
+
+	for _, f := range ht.Fairways {
+		o := h.Waypoints(f.Tee, f.Pin)
+		if len(o) == 0 {
+			h.Fairways = append(h.Fairways, f)
+		} else if !sameLocs(o, f.Path) {
+			mc = append(mc, MergeConflict{Hole: h.ID, Kind: "fairway", ID: f.Tee + "->" + f.Pin,
+				Ours: fmt.Sprintf("%d waypoints", len(o)), Theirs: fmt.Sprintf("%d waypoints", len(f.Path)), Path: f.Path})
+		}
+	}
+
+	for _, zt := range ht.Hazards {
+		found := false
+		for _, zo := range h.Hazards {
+			if zo.ID != zt.ID {
+				continue
+			}
+			found = true
+			if zo.Type != zt.Type || !sameLocs(zo.Poly, zt.Poly) {
+				mc = append(mc, MergeConflict{Hole: h.ID, Kind: "hazard", ID: zt.ID, Ours: zo.Type, Theirs: zt.Type, Path: zt.Poly})
+			}
+		}
+		if !found {
+			h.Hazards = append(h.Hazards, zt)
+		}
+	}
+
+	for _, mt := range ht.Mandos {
+		found := false
+		for _, mo := range h.Mandos {
+			if mo.ID != mt.ID {
+				continue
+			}
+			found = true
+			if mo.Side != mt.Side || !sameLocs(mo.Line, mt.Line) {
+				mc = append(mc, MergeConflict{Hole: h.ID, Kind: "mando", ID: mt.ID, Ours: mo.Side, Theirs: mt.Side, Path: mt.Line})
+			}
+		}
+		if !found {
+			h.Mandos = append(h.Mandos, mt)
+		}
+	}
+
+	for _, dt := range ht.DropZones {
+		found := false
+		for k, do := range h.DropZones {
+			if do.ID != dt.ID {
+				continue
+			}
+			found = true
+			if d := Dist(do.Loc, dt.Loc); d <= MergeAgreeDist {
+				h.DropZones[k].Loc = midLoc(do.Loc, dt.Loc)
+			} else {
+				mc = append(mc, MergeConflict{Hole: h.ID, Kind: "drop zone", ID: dt.ID, Dist: d, Loc: dt.Loc})
+			}
+		}
+		if !found {
+			h.DropZones = append(h.DropZones, dt)
+		}
+	}
+
+	return mc
+}
+
+// Settles conflict i, taking their version if theirs is set and otherwise
+// keeping ours
+func (c *Course) ResolveConflict(i int, theirs bool) {
+	if i < 0 || i >= len(c.Conflicts) {
+		return
+	}
+	mc := c.Conflicts[i]
+	c.Conflicts = append(c.Conflicts[:i], c.Conflicts[i+1:]...)
+	if !theirs {
+		return
+	}
+
+	for j := range c.Holes {
+		h := &c.Holes[j]
+		if h.ID != mc.Hole {
+			continue
+		}
+
+		switch mc.Kind {
+		case "tee":
+			for k := range h.Tees {
+				if h.Tees[k].ID == mc.ID {
+					h.Tees[k].Loc = mc.Loc
+				}
+			}
+		case "pin":
+			for k := range h.Pins {
+				if h.Pins[k].ID == mc.ID {
+					h.Pins[k].Loc = mc.Loc
+				}
+			}
+		case "drop zone":
+			for k := range h.DropZones {
+				if h.DropZones[k].ID == mc.ID {
+					h.DropZones[k].Loc = mc.Loc
+				}
+			}
+		case "par":
+			tp := strings.SplitN(mc.ID, "->", 2)
+			for k := range h.Pars {
+				if len(tp) == 2 && h.Pars[k].Tee == tp[0] && h.Pars[k].Pin == tp[1] {
+					h.Pars[k].Par = mc.Par
+				}
+			}
+		case "fairway":
+			if tp := strings.SplitN(mc.ID, "->", 2); len(tp) == 2 {
+				h.SetWaypoints(tp[0], tp[1], mc.Path)
+			}
+		case "hazard":
+			for k := range h.Hazards {
+				if h.Hazards[k].ID == mc.ID {
+					h.Hazards[k].Type = mc.Theirs
+					h.Hazards[k].Poly = mc.Path
+				}
+			}
+		case "mando":
+			for k := range h.Mandos {
+				if h.Mandos[k].ID == mc.ID {
+					h.Mandos[k].Side = mc.Theirs
+					h.Mandos[k].Line = mc.Path
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package rnd
+
+import "testing"
+
+func oursCourse() Course {
+	return Course{
+		ID:  "merge",
+		Loc: testTee,
+		Holes: []Hole{{
+			ID:   "1",
+			Tees: []Tee{{ID: "reg", Loc: at(0, 0)}},
+			Pins: []Pin{{ID: "A", Loc: at(0, 100)}},
+			Pars: []Par{{Tee: "reg", Pin: "A", Par: 3}},
+		}},
+	}
+}
+
+// Their survey has the tee a meter over, the pin 10 m further on with a
+// different par, a long tee and a second pin with no par, and another hole
+func theirsCourse() Course {
+	return Course{
+		ID:  "merge",
+		Loc: testTee,
+		Holes: []Hole{
+			{
+				ID:   "1",
+				Tees: []Tee{{ID: "reg", Loc: at(1, 0)}, {ID: "pro", Loc: at(0, -40)}},
+				Pins: []Pin{{ID: "A", Loc: at(0, 110)}, {ID: "B", Loc: at(30, 100)}},
+				Pars: []Par{{Tee: "reg", Pin: "A", Par: 4}, {Tee: "pro", Pin: "A", Par: 4}},
+			},
+			{
+				ID:   "7",
+				Tees: []Tee{{ID: "reg", Loc: at(500, 0)}},
+				Pins: []Pin{{ID: "A", Loc: at(500, 100)}},
+				Pars: []Par{{Tee: "reg", Pin: "A", Par: 3}},
+			},
+		},
+	}
+}
+
+func hasChange(cc []CourseChange, hole string, kind string, id string, change string) bool {
+	for _, c := range cc {
+		if c.Hole == hole && c.Kind == kind && c.ID == id && c.Change == change {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDiffCourses(t *testing.T) {
+	cc := DiffCourses(oursCourse(), theirsCourse())
+
+	tests := []struct {
+		hole   string
+		kind   string
+		id     string
+		change string
+	}{
+		{"1", "tee", "reg", "moved"},
+		{"1", "pin", "A", "moved"},
+		{"1", "tee", "pro", "added"},
+		{"1", "pin", "B", "added"},
+		{"1", "par", "reg->A", "changed"},
+		{"7", "hole", "", "added"},
+	}
+	for _, tt := range tests {
+		if !hasChange(cc, tt.hole, tt.kind, tt.id, tt.change) {
+			t.Errorf("no %s %s %s on hole %s in %v", tt.kind, tt.id, tt.change, tt.hole, cc)
+		}
+	}
+	if len(cc) != len(tests) {
+		t.Errorf("%d changes, want %d: %v", len(cc), len(tests), cc)
+	}
+
+	// the same course surveyed under other IDs is only renamed
+	b := oursCourse()
+	b.Holes[0].ID = "one"
+	b.Holes[0].Tees[0].ID = "r"
+	b.Holes[0].Pars[0].Tee = "r"
+	cc = DiffCourses(oursCourse(), b)
+	if !hasChange(cc, "1", "hole", "1", "renamed") || !hasChange(cc, "1", "tee", "reg", "renamed") || len(cc) != 2 {
+		t.Errorf("renamed survey: %v", cc)
+	}
+
+	if cc := DiffCourses(oursCourse(), oursCourse()); len(cc) != 0 {
+		t.Errorf("same course: %v", cc)
+	}
+}
+
+func TestMergeCourses(t *testing.T) {
+	m := MergeCourses(oursCourse(), theirsCourse())
+
+	if len(m.Holes) != 2 || m.GetHole("7").ID == "" {
+		t.Fatalf("merged holes %d, want theirs added", len(m.Holes))
+	}
+	h := m.GetHole("1")
+	if d := Dist(h.GetTee("reg").Loc, at(0.5, 0)); d > 0.05 {
+		t.Errorf("agreeing tees not averaged, %.2f m off", d)
+	}
+	if d := Dist(h.GetPin("A").Loc, at(0, 100)); d > 0.05 {
+		t.Errorf("disagreeing pin moved %.2f m from ours", d)
+	}
+
+	pars := []struct {
+		tee  string
+		pin  string
+		par  int
+		have bool
+	}{
+		{"reg", "A", 3, true},
+		{"pro", "A", 4, true},
+		// neither survey has a par for the new pin
+		{"reg", "B", 0, false},
+		{"pro", "B", 0, false},
+	}
+	for _, p := range pars {
+		if h.hasPar(p.tee, p.pin) != p.have || (p.have && h.Par(p.tee, p.pin) != p.par) {
+			t.Errorf("par %s->%s: have %v par %d, want %v %d", p.tee, p.pin, h.hasPar(p.tee, p.pin), h.Par(p.tee, p.pin), p.have, p.par)
+		}
+	}
+
+	if len(m.Conflicts) != 2 {
+		t.Fatalf("conflicts %v, want the pin and the par", m.Conflicts)
+	}
+	for _, mc := range m.Conflicts {
+		if !(mc.Kind == "pin" && mc.ID == "A") && !(mc.Kind == "par" && mc.ID == "reg->A") {
+			t.Errorf("unexpected conflict %v", mc)
+		}
+	}
+}
+
+func TestResolveConflict(t *testing.T) {
+	m := MergeCourses(oursCourse(), theirsCourse())
+	pinConflict := func() int {
+		for i, mc := range m.Conflicts {
+			if mc.Kind == "pin" {
+				return i
+			}
+		}
+		return -1
+	}
+
+	// out of range is left alone
+	m.ResolveConflict(len(m.Conflicts), true)
+	if len(m.Conflicts) != 2 {
+		t.Fatalf("%d conflicts after resolving one that isn't there", len(m.Conflicts))
+	}
+
+	m.ResolveConflict(pinConflict(), true)
+	if d := Dist(m.GetHole("1").GetPin("A").Loc, at(0, 110)); d > 0.05 {
+		t.Errorf("taking theirs left the pin %.2f m off", d)
+	}
+
+	m.ResolveConflict(0, false)
+	if len(m.Conflicts) != 0 {
+		t.Errorf("conflicts left %v", m.Conflicts)
+	}
+	if p := m.GetHole("1").Par("reg", "A"); p != 3 {
+		t.Errorf("keeping ours changed the par to %d", p)
+	}
+
+	m = MergeCourses(oursCourse(), theirsCourse())
+	for len(m.Conflicts) > 0 {
+		m.ResolveConflict(0, true)
+	}
+	if p := m.GetHole("1").Par("reg", "A"); p != 4 {
+		t.Errorf("taking theirs left par %d, want 4", p)
+	}
+}
+
+func TestSetParRowMerged(t *testing.T) {
+	m := MergeCourses(oursCourse(), theirsCourse())
+
+	// hole 1 is listed reg->A, reg->B, pro->A, pro->B whatever order the
+	// merge left its pars in, then hole 7
+	edits := []struct {
+		row  int
+		hole string
+		tee  string
+		pin  string
+	}{
+		{2, "1", "pro", "A"},
+		{1, "1", "reg", "B"},
+		{4, "7", "reg", "A"},
+	}
+	for _, e := range edits {
+		if !m.SetParRow(e.row, 5) {
+			t.Fatalf("row %d not found", e.row)
+		}
+		if p := m.GetHole(e.hole).Par(e.tee, e.pin); p != 5 {
+			t.Errorf("row %d: hole %s %s->%s par %d, want 5", e.row, e.hole, e.tee, e.pin, p)
+		}
+	}
+	if p := m.GetHole("1").Par("reg", "A"); p != 3 {
+		t.Errorf("unedited reg->A par became %d", p)
+	}
+	if m.SetParRow(5, 5) {
+		t.Error("set a par on a row past the end")
+	}
+}
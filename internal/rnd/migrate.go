@@ -0,0 +1,68 @@
+package rnd
+
+import "fmt"
+
+// Copies the courses, their revisions and the disc catalog from a directory
+// laid out like the data home, such as the data directory of a checkout from
+// before there was a data home. Courses already in the store are left alone
+// unless overwrite is set. Returns what was copied.
+func ImportData(dir string, overwrite bool) ([]string, error) {
+	src := NewFSStore(dir)
+	dst := DefaultStore()
+	var copied []string
+
+	// the course index is built from the courses in the store
+	defer func() { courseIndex = nil }()
+
+	ids, err := src.CourseIDs()
+	if err != nil {
+		return copied, err
+	}
+	for _, id := range ids {
+		if _, err := dst.ReadCourse(id); err == nil && !overwrite {
+			fmt.Println(id + " is already in the store, skipping it")
+			continue
+		}
+
+		b, err := src.ReadCourse(id)
+		if err != nil {
+			return copied, err
+		}
+		if err := dst.WriteCourse(id, b); err != nil {
+			return copied, err
+		}
+		copied = append(copied, "courses/"+id)
+
+		revs, err := src.CourseRevisions(id)
+		if err != nil {
+			return copied, err
+		}
+		for _, rev := range revs {
+			b, err := src.ReadCourseRevision(id, rev)
+			if err != nil {
+				return copied, err
+			}
+			if err := dst.WriteCourseRevision(id, rev, b); err != nil {
+				return copied, err
+			}
+			copied = append(copied, "courses/"+id+"/"+rev)
+		}
+	}
+
+	b, err := src.ReadDiscCatalog()
+	if err == ErrNotFound {
+		return copied, nil
+	}
+	if err != nil {
+		return copied, err
+	}
+	if _, err := dst.ReadDiscCatalog(); err == nil && !overwrite {
+		fmt.Println("The store already has a disc catalog, skipping it")
+		return copied, nil
+	}
+	if err := dst.WriteDiscCatalog(b); err != nil {
+		return copied, err
+	}
+	copied = append(copied, "discs.csv")
+	return copied, nil
+}
@@ -0,0 +1,102 @@
+package rnd
+
+import "math"
+
+// Playing lengths in meters from which a hole is suggested as a par 4 and
+// a par 5, about 440 ft and 750 ft. These match most of the pars of the
+// courses in data/courses.
+var (
+	Par4Length = 135.0
+	Par5Length = 230.0
+)
+
+// Rounds of history that count as much as the length does when suggesting
+// a par, and the most the history can count for however often the hole has
+// been played. One player's average runs above par, so it only nudges: with
+// a share of a half, the average has to be a full stroke off the length's
+// par to move the suggestion.
+var (
+	ParHistoryWeight   = 10.0
+	MaxParHistoryShare = 0.5
+)
+
+// Par for a tee/pin from its elevation adjusted length alone
+func lengthPar(playsLike float64) int {
+	switch {
+	case playsLike >= Par5Length:
+		return 5
+	case playsLike >= Par4Length:
+		return 4
+	}
+	return 3
+}
+
+// Suggested par for a tee/pin from its playing length and, if it has been
+// played, the scoring history in hs
+func (h Hole) SuggestPar(tID string, pID string, hs HoleStats) int {
+	par := float64(lengthPar(h.PlaysLike(tID, pID)))
+
+	if hs.Played > 0 {
+		w := float64(hs.Played) / (float64(hs.Played) + ParHistoryWeight)
+		w = math.Min(w, MaxParHistoryShare)
+		par = (1-w)*par + w*hs.Average
+	}
+
+	return int(math.Max(3, math.Min(5, math.Round(par))))
+}
+
+// Sets the par of a tee/pin, adding it if the hole doesn't have one
+func (h *Hole) SetPar(tID string, pID string, par int) {
+	for k, p := range h.Pars {
+		if p.Tee == tID && p.Pin == pID {
+			h.Pars[k].Par = par
+			return
+		}
+	}
+	h.Pars = append(h.Pars, Par{Tee: tID, Pin: pID, Par: par})
+}
+
+// Sets the par on a row of edit-course's table, which lists the tee/pins of
+// each hole tee by tee. Returns false if there is no such row.
+func (c *Course) SetParRow(row int, par int) bool {
+	idx := 0
+	for i, h := range c.Holes {
+		for _, t := range h.Tees {
+			for _, p := range h.Pins {
+				if idx == row {
+					c.Holes[i].SetPar(t.ID, p.ID, par)
+					return true
+				}
+				idx++
+			}
+		}
+	}
+	return false
+}
+
+// Fills the par of every tee/pin of every hole that doesn't have one with its
+// suggestion, using the scoring history saved by make-stats if there is any.
+// With overwrite the pars already set are replaced too. Pars are laid out
+// tee by tee, the order edit-course's table expects.
+func (c *Course) SuggestPars(overwrite bool) {
+	cs, _ := LoadCourseStats(c.ID)
+
+	for i, h := range c.Holes {
+		var pars []Par
+		for _, t := range h.Tees {
+			for _, p := range h.Pins {
+				if par := h.Par(t.ID, p.ID); !overwrite && par > 0 && par != 99 {
+					pars = append(pars, Par{Tee: t.ID, Pin: p.ID, Par: par})
+					continue
+				}
+				hs, _ := cs.GetHole(h.ID, t.ID, p.ID)
+				pars = append(pars, Par{
+					Tee: t.ID,
+					Pin: p.ID,
+					Par: h.SuggestPar(t.ID, p.ID, hs),
+				})
+			}
+		}
+		c.Holes[i].Pars = pars
+	}
+}
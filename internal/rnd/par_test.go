@@ -0,0 +1,46 @@
+package rnd
+
+import "testing"
+
+func TestSuggestPar(t *testing.T) {
+	h := testCourse().Holes[0]
+
+	tests := []struct {
+		name string
+		hs   HoleStats
+		want int
+	}{
+		{"unplayed", HoleStats{}, 3},
+		{"a little over", HoleStats{Played: 500, Average: 3.8}, 3},
+		{"a stroke over", HoleStats{Played: 500, Average: 4.2}, 4},
+		// however often it's been played the history only nudges
+		{"well over", HoleStats{Played: 500, Average: 5.5}, 4},
+	}
+	for _, tt := range tests {
+		if got := h.SuggestPar("reg", "A", tt.hs); got != tt.want {
+			t.Errorf("%s: par %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestParsKeepsSetPars(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemStore())
+
+	c := testCourse()
+	c.Holes[0].Pins = append(c.Holes[0].Pins, Pin{ID: "B", Loc: at(0, 60)})
+	c.Holes[0].Pars[0].Par = 5
+
+	c.SuggestPars(false)
+	if p := c.Holes[0].Par("reg", "A"); p != 5 {
+		t.Errorf("hand set par became %d", p)
+	}
+	if p := c.Holes[0].Par("reg", "B"); p != 3 {
+		t.Errorf("missing par filled with %d, want 3", p)
+	}
+
+	c.SuggestPars(true)
+	if p := c.Holes[0].Par("reg", "A"); p != 3 {
+		t.Errorf("overwritten par %d, want 3", p)
+	}
+}
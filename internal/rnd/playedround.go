@@ -1,6 +1,7 @@
 package rnd
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -9,21 +10,30 @@ import (
 	"log"
 	"math"
 	"os"
-	"path"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
 )
 
 type RoundRow struct {
-	RowNum int     `json:"-"`
-	HoleID string  `json:"hole"`
-	TeeID  string  `json:"tee"`
-	PinID  string  `json:"pin"`
-	Lat    float64 `json:"lat"`
-	Lon    float64 `json:"lon"`
-	Disc   string  `json:"disc"`
+	RowNum  int     `json:"-"`
+	HoleID  string  `json:"hole"`
+	TeeID   string  `json:"tee"`
+	PinID   string  `json:"pin"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	Disc    string  `json:"disc"`
+	Penalty int     `json:"penalty"`
+	Mando   string  `json:"mando"`
+	Alt     float64 `json:"alt"`
+	HasAlt  bool    `json:"has_alt"`
+
+	// set when the throw was made from one of the hole's drop zones
+	DropZone string `json:"drop_zone"`
+
+	// where the missed mando rule has the next throw played from, set along
+	// with Mando. The next row keeps where the disc came to rest.
+	MandoLie Loc `json:"mando_lie,omitempty"`
 }
 
 type RoundTable []RoundRow
@@ -32,20 +42,25 @@ type Round struct {
 	ID         string     `json:"roundID"`
 	CourseID   string     `json:"courseID"`
 	CourseName string     `json:"courseName"`
+	CourseRev  string     `json:"courseRev"`
+	LayoutID   string     `json:"layout"`
 	Course     Course     `json:"-"`
 	Data       RoundTable `json:"roundData"`
 	Notes      string     `json:"notes"`
 }
 
 type HoleScoreSummary struct {
-	Hole   string  `json:"hole"`
-	Tee    string  `json:"tee"`
-	Pin    string  `json:"pin"`
-	Dist   float64 `json:"dist"`
-	Par    int     `json:"par"`
-	Score  int     `json:"score"`
-	Result int     `json:"res"`
-	Total  int     `json:"tot"`
+	Hole      string  `json:"hole"`
+	Tee       string  `json:"tee"`
+	Pin       string  `json:"pin"`
+	Dist      float64 `json:"dist"`
+	Elev      float64 `json:"elev"`
+	PlaysLike float64 `json:"plays_like"`
+	Par       int     `json:"par"`
+	Score     int     `json:"score"`
+	Result    int     `json:"res"`
+	Total     int     `json:"tot"`
+	Penalty   int     `json:"pen"`
 }
 type RoundScoreSummary []HoleScoreSummary
 
@@ -59,16 +74,21 @@ type RoundGEOJSON struct {
 	Discs    []Disc            `json:"discs"`
 	Features []Feature         `json:"features"`
 	Table    RoundScoreSummary `json:"table"`
+	Units    string            `json:"units"`
 }
 
 func (rr RoundRow) Loc() Loc {
+	if rr.HasAlt {
+		return Loc{rr.Lat, rr.Lon, rr.Alt}
+	}
 	return Loc{rr.Lat, rr.Lon}
 }
 
-func RootDir() string {
-	_, b, _, _ := runtime.Caller(0)
-	d := path.Join(path.Dir(b))
-	return filepath.Dir(d)
+func (rr *RoundRow) setLoc(l Loc) {
+	rr.Lat = l[0]
+	rr.Lon = l[1]
+	rr.Alt = l.Alt()
+	rr.HasAlt = l.HasAlt()
 }
 
 func inferHole(l Loc, c Course) (Hole, Tee) {
@@ -92,6 +112,47 @@ func inferHole(l Loc, c Course) (Hole, Tee) {
 	return bestH, bestT
 }
 
+func inferTee(l Loc, h Hole) (Tee, float64) {
+	bestT := Tee{}
+	best_dist := math.Inf(1)
+
+	for _, t := range h.Tees {
+		this_dist := Dist(t.Loc, l)
+
+		if this_dist < best_dist {
+			bestT = t
+			best_dist = this_dist
+		}
+	}
+
+	return bestT, best_dist
+}
+
+// The hole a round starts on, tees near the first stamp are told apart by
+// which of their pins the following stamps get closest to
+func inferStartHole(ts Stamps, c Course, teeThresh float64, date string) (Hole, Tee) {
+	bestH, bestT := inferHole(ts[0].Loc, c)
+	best_dist := math.Inf(1)
+
+	for _, h := range c.Holes {
+		t, d_tee := inferTee(ts[0].Loc, h)
+		if d_tee > teeThresh {
+			continue
+		}
+
+		for i := 1; i < len(ts) && i < 10; i++ {
+			p := inferPinOn(ts[i].Loc, h, c, date)
+			if this_dist := Dist(ts[i].Loc, p.Loc); this_dist < best_dist {
+				bestH = h
+				bestT = t
+				best_dist = this_dist
+			}
+		}
+	}
+
+	return bestH, bestT
+}
+
 func inferPin(l Loc, h Hole) Pin {
 	bestP := Pin{}
 	best_dist := 9999.9
@@ -108,6 +169,22 @@ func inferPin(l Loc, h Hole) Pin {
 	return bestP
 }
 
+func inferDropZone(l Loc, h Hole) (DropZone, float64) {
+	bestD := DropZone{}
+	best_dist := math.Inf(1)
+
+	for _, d := range h.AllDropZones() {
+		this_dist := Dist(d.Loc, l)
+
+		if this_dist < best_dist {
+			bestD = d
+			best_dist = this_dist
+		}
+	}
+
+	return bestD, best_dist
+}
+
 func (rt RoundTable) getStamps() Stamps {
 	s := Stamps{}
 	for _, r := range rt {
@@ -129,13 +206,33 @@ func insert(s Stamps, i int, value Stamp) Stamps {
 	return s
 }
 
+// The tee and pin each hole of the round was played with
+func (rt RoundTable) Played() []LayoutHole {
+	var played []LayoutHole
+	for i, r := range rt {
+		if i == 0 || r.HoleID != rt[i-1].HoleID {
+			played = append(played, LayoutHole{
+				Hole: r.HoleID,
+				Tee:  r.TeeID,
+				Pin:  r.PinID,
+			})
+		}
+	}
+	return played
+}
+
+func (r *Round) setLayout() {
+	r.LayoutID = r.Course.InferLayout(r.Data.Played()).ID
+}
+
 func (r *Round) DeleteLine(idx int) {
 	ts := r.Data.getStamps()
 	ts = remove(ts, idx)
 	c := r.Course
-	rt := ProcessStamps(ts, c)
+	rt := ProcessStamps(ts, c, r.Date())
 
 	r.Data = rt
+	r.setLayout()
 }
 
 func (r *Round) AddLine(idx int, lat float64, lon float64) {
@@ -146,39 +243,63 @@ func (r *Round) AddLine(idx int, lat float64, lon float64) {
 	}
 	ts = insert(ts, idx, s)
 	c := r.Course
-	rt := ProcessStamps(ts, c)
+	rt := ProcessStamps(ts, c, r.Date())
 
 	r.Data = rt
+	r.setLayout()
 }
 
 func (r *Round) MovePoint(idx int, lat float64, lon float64) {
 	ts := r.Data.getStamps()
-	ts[idx].Loc = Loc{lat, lon}
+	if ts[idx].Loc.HasAlt() {
+		ts[idx].Loc = Loc{lat, lon, ts[idx].Loc.Alt()}
+	} else {
+		ts[idx].Loc = Loc{lat, lon}
+	}
 	c := r.Course
-	rt := ProcessStamps(ts, c)
+	rt := ProcessStamps(ts, c, r.Date())
 
 	r.Data = rt
+	r.setLayout()
 }
 
-func ProcessStamps(ts Stamps, c Course) RoundTable {
+// Splits stamps into holes and throws. Pins come from the course's rotation
+// on date ("2006-01-02...") where it has one and are inferred from the putts
+// otherwise.
+func ProcessStamps(ts Stamps, c Course, date string) RoundTable {
 	teeThresh := 10.0
 	pinThresh := 10.0
 	driveThresh := 20.0
+	seqThresh := 30.0
+	dropThresh := 10.0
 
 	var RT RoundTable
 
-	h, t := inferHole(ts[0].Loc, c)
+	h, t := inferStartHole(ts, c, teeThresh, date)
 
 	for i, s := range ts {
 
-		h_n, t_n := inferHole(s.Loc, c)
-		d_tee := Dist(s.Loc, t_n.Loc)
+		// the next hole in the sequence wins over a closer tee of another hole
+		h_n := c.NextHole(h.ID)
+		t_n, d_tee := inferTee(s.Loc, h_n)
+		if d_tee > seqThresh {
+			h_n, t_n = inferHole(s.Loc, c)
+			d_tee = Dist(s.Loc, t_n.Loc)
+		} else {
+			d_tee = d_tee * teeThresh / seqThresh
+		}
 
 		d_pin := 999.9
 		p := Pin{}
+		pinReach := pinThresh
 		if i > 0 {
-			p = inferPin(ts[i-1].Loc, h)
+			p = inferPinOn(ts[i-1].Loc, h, c, date)
 			d_pin = Dist(ts[i-1].Loc, p.Loc)
+
+			// with the pin known from the rotation a sloppy last putt will do
+			if _, ok := c.ActivePin(h.ID, date); ok {
+				pinReach = 2 * pinThresh
+			}
 		}
 
 		nextDriveDist := 0.0
@@ -186,7 +307,12 @@ func ProcessStamps(ts Stamps, c Course) RoundTable {
 			nextDriveDist = Dist(ts[i+1].Loc, ts[i].Loc)
 		}
 
-		if i > 0 && d_tee < teeThresh && d_pin < pinThresh && nextDriveDist > driveThresh {
+		// a throw from one of this hole's drop zones keeps playing it, even
+		// when the drop zone is near the next tee
+		dz, d_dz := inferDropZone(s.Loc, h)
+		fromDrop := i > 0 && d_dz < dropThresh && d_dz <= Dist(s.Loc, t_n.Loc)
+
+		if i > 0 && !fromDrop && h_n.ID != h.ID && d_tee < teeThresh && d_pin < pinReach && nextDriveDist > driveThresh {
 			// then roll to the next hole
 			h = h_n
 			t = t_n
@@ -201,8 +327,14 @@ func ProcessStamps(ts Stamps, c Course) RoundTable {
 			PinID:  "",
 			Lat:    s.Loc[0],
 			Lon:    s.Loc[1],
+			Alt:    s.Loc.Alt(),
+			HasAlt: s.Loc.HasAlt(),
 			Disc:   s.Disc}
 
+		if fromDrop {
+			rr.DropZone = dz.ID
+		}
+
 		if i == len(ts)-1 {
 			rr.PinID = p.ID
 		}
@@ -222,30 +354,78 @@ func ProcessStamps(ts Stamps, c Course) RoundTable {
 		RT[i].RowNum = i
 	}
 
+	RT.assignPenalties(c)
+
 	return RT
 }
 
+// Marks each throw that goes into an ob or hazard area or misses a mando.
+// A throw followed by one from a drop zone went ob even if the drop zone
+// itself is in bounds.
+func (rt RoundTable) assignPenalties(c Course) {
+	for i := range rt {
+		rt[i].Penalty = 0
+		rt[i].Mando = ""
+		rt[i].MandoLie = nil
+		if i == len(rt)-1 || rt[i+1].HoleID != rt[i].HoleID {
+			continue
+		}
+		h := c.GetHole(rt[i].HoleID)
+		from := rt.ThrownFrom(i)
+		rt[i].Penalty = h.ThrowPenalty(from, rt[i+1].Loc())
+
+		if m, missed := h.MissedMando(from, rt[i+1].Loc()); missed {
+			rt[i].Penalty++
+			rt[i].Mando = m.ID
+			rt[i].MandoLie = c.MandoLie(m, from)
+		}
+
+		if rt[i+1].DropZone != "" && rt[i].Penalty == 0 {
+			rt[i].Penalty = 1
+		}
+	}
+}
+
+// Where throw i was played from. After a missed mando that's wherever the
+// rule put the disc rather than where it came to rest.
+func (rt RoundTable) ThrownFrom(i int) Loc {
+	if i > 0 && len(rt[i-1].MandoLie) >= 2 && rt[i-1].HoleID == rt[i].HoleID {
+		return rt[i-1].MandoLie
+	}
+	return rt[i].Loc()
+}
+
+// Saves the round to the store
 func (r Round) WriteCSV() {
-	f, err := os.Create(r.ID + ".csv")
-	if err != nil {
+	var b bytes.Buffer
+	r.writeCSV(&b)
+	if err := DefaultStore().WriteRound(r.ID, b.Bytes()); err != nil {
 		fmt.Println(err)
 	}
-	defer f.Close()
+}
 
+func (r Round) writeCSV(f io.Writer) {
 	w := csv.NewWriter(f)
 	defer w.Flush()
 	w.Write([]string{"RoundID: " + r.ID})
 	w.Write([]string{"CourseID: " + r.CourseID})
 	w.Write([]string{"CourseName: " + r.CourseName})
+	w.Write([]string{"CourseRev: " + r.CourseRev})
+	w.Write([]string{"Layout: " + r.LayoutID})
 	w.Write([]string{"Notes: " + r.Notes})
 	w.Write([]string{""})
-	w.Write([]string{"hole", "tee", "pin", "par", "lat", "lon", "disc"})
+	w.Write([]string{"hole", "tee", "pin", "par", "lat", "lon", "disc", "penalty", "mando", "alt", "drop_zone"})
 
 	for _, l := range r.Data {
 
 		h := r.Course.GetHole(l.HoleID)
 		par := h.Par(l.TeeID, l.PinID)
 
+		alt := ""
+		if l.HasAlt {
+			alt = fmt.Sprintf("%f", l.Alt)
+		}
+
 		w.Write([]string{
 			l.HoleID,
 			l.TeeID,
@@ -254,24 +434,52 @@ func (r Round) WriteCSV() {
 			fmt.Sprintf("%f", l.Lat),
 			fmt.Sprintf("%f", l.Lon),
 			l.Disc,
+			strconv.Itoa(l.Penalty),
+			l.Mando,
+			alt,
+			l.DropZone,
 		})
 	}
 }
 
+// Dumps the round next to the editors' map data, for a look at it
 func (r Round) WriteJSON() {
 	file, _ := json.MarshalIndent(r, "", "	")
-	_ = ioutil.WriteFile(r.ID+".json", file, 0644)
+	_ = ioutil.WriteFile(filepath.Join(VisDir(), r.ID+".json"), file, 0644)
 }
 
 func (r Round) Cleanup() {
 
+	// holes whose pin wasn't worked out get the one in rotation that day
+	for i, l := range r.Data {
+		h := r.Course.GetHole(l.HoleID)
+		if h.GetPin(l.PinID).ID != "" {
+			continue
+		}
+		if pID, ok := r.Course.ActivePin(l.HoleID, r.Date()); ok {
+			r.Data[i].PinID = pID
+		}
+	}
+
+	// throws made from a drop zone are from the drop zone itself. Throws
+	// after a missed mando keep where the disc came to rest so the miss is
+	// still there when the round is split up again.
+	for i, l := range r.Data {
+		if l.DropZone == "" {
+			continue
+		}
+		d := r.Course.GetHole(l.HoleID).GetDropZone(l.DropZone)
+		if len(d.Loc) >= 2 {
+			r.Data[i].setLoc(d.Loc)
+		}
+	}
+
 	cur_hole := ""
 	for i, l := range r.Data {
 		if l.HoleID != cur_hole {
 			h := r.Course.GetHole(l.HoleID)
 			t := h.GetTee(l.TeeID)
-			r.Data[i].Lat = t.Loc[0]
-			r.Data[i].Lon = t.Loc[1]
+			r.Data[i].setLoc(t.Loc)
 			cur_hole = l.HoleID
 		}
 
@@ -279,29 +487,57 @@ func (r Round) Cleanup() {
 			r.Data[i].Disc = "BASKET"
 			h := r.Course.GetHole(l.HoleID)
 			p := h.GetPin(l.PinID)
-			r.Data[i].Lat = p.Loc[0]
-			r.Data[i].Lon = p.Loc[1]
+			r.Data[i].setLoc(p.Loc)
 		}
 	}
 
 }
 
+// Round IDs start with the date the round was played
+func (r Round) Date() string {
+	if len(r.ID) < 10 {
+		return ""
+	}
+	return r.ID[:10]
+}
+
+// When the round started, YYYY-MM-DD-HH-MM-SS, or just the date if the ID
+// doesn't say
+func (r Round) Time() string {
+	if len(r.ID) < 19 {
+		return r.Date()
+	}
+	return r.ID[:19]
+}
+
 func GetRound(ts Stamps, fileID string) Round {
+	c := inferCourse(ts[0].Loc)
+	if c.ID == "" {
+		log.Fatal("No course found near the start of the round")
+	}
+	return GetRoundOnCourse(ts, fileID, c)
+}
+
+func GetRoundOnCourse(ts Stamps, fileID string, course Course) Round {
 
-	course := inferCourse(ts[0].Loc)
+	// score against the layout that was current when the round was played
+	if CourseExists(course.ID) {
+		course = GetCourseAt(course.ID, fileID)
+	}
 
 	rndID := fileID + "_-_" + course.ID
 
 	fmt.Println(rndID)
-	RT := ProcessStamps(ts, course)
 
 	R := Round{
 		ID:         rndID,
 		CourseID:   course.ID,
 		CourseName: course.Name,
-		Data:       RT,
+		CourseRev:  course.Effective,
 		Course:     course,
 	}
+	R.Data = ProcessStamps(ts, course, R.Date())
+	R.setLayout()
 
 	return R
 }
@@ -317,16 +553,12 @@ type discCSVRow struct {
 type discCSV []discCSVRow
 
 func GetDiscs() discCSV {
-	homedir, _ := os.UserHomeDir()
-	filename := filepath.Join(homedir, ".discgolf", "discs", "discs.csv")
-
-	f, err := os.Open(filename)
+	b, err := DefaultStore().ReadDiscs()
 	if err != nil {
-		fmt.Println(err)
+		fmt.Println("discs: " + err.Error())
 	}
-	defer f.Close()
 
-	r := csv.NewReader(f)
+	r := csv.NewReader(bytes.NewReader(b))
 	r.Read() //burn header
 
 	var ts discCSV
@@ -345,69 +577,85 @@ func GetDiscs() discCSV {
 	return ts
 }
 
-func (rt Round) DrawSummary() {
-
-	var features []Feature
-
-	all_discs := GetDiscs()
-	used_discs := []Disc{{"UNDEFINED", "blank.png"}, {"BASKET", "basket.png"}}
-
-	for _, d := range all_discs {
-		used_discs = append(used_discs, Disc{
-			ID:    d.Name,
-			Image: d.Image,
-		})
-	}
-
-	c := rt.Course
-
+// Strokes, penalties included, taken on each hole in the order played
+func (rt Round) HoleScores() RoundScoreSummary {
 	var RSS RoundScoreSummary
 	cur_hole := rt.Data[0].HoleID
 	cur_tot := 0
 	hole_tot := -2
+	hole_pen := 0
 	for i, r := range rt.Data {
 		hole_tot++
 
 		if i > 0 && r.HoleID != cur_hole {
 			rp := rt.Data[i-1]
-			h := c.GetHole(rp.HoleID)
+			h := rt.Course.GetHole(rp.HoleID)
 			t := h.GetTee(rp.TeeID)
 			p := h.GetPin(rp.PinID)
-			result := hole_tot - h.Par(t.ID, p.ID)
+			result := hole_tot + hole_pen - h.Par(t.ID, p.ID)
 			cur_tot = cur_tot + result
 			RSS = append(RSS, HoleScoreSummary{
-				Hole:   h.ID,
-				Tee:    t.ID,
-				Pin:    p.ID,
-				Dist:   math.Round(3.281 * Dist(t.Loc, p.Loc)),
-				Par:    h.Par(t.ID, p.ID),
-				Score:  hole_tot,
-				Result: result,
-				Total:  cur_tot,
+				Hole:      h.ID,
+				Tee:       t.ID,
+				Pin:       p.ID,
+				Dist:      InUnits(h.Length(t.ID, p.ID)),
+				Elev:      InUnits(h.Elevation(t.ID, p.ID)),
+				PlaysLike: InUnits(h.PlaysLike(t.ID, p.ID)),
+				Par:       h.Par(t.ID, p.ID),
+				Score:     hole_tot + hole_pen,
+				Result:    result,
+				Total:     cur_tot,
+				Penalty:   hole_pen,
 			})
 
 			cur_hole = r.HoleID
 			hole_tot = -1
+			hole_pen = 0
 		}
+		hole_pen += r.Penalty
 	}
 	hole_tot++
 	rp := rt.Data[len(rt.Data)-1]
-	h := c.GetHole(rp.HoleID)
+	h := rt.Course.GetHole(rp.HoleID)
 	t := h.GetTee(rp.TeeID)
 	p := h.GetPin(rp.PinID)
-	result := hole_tot - h.Par(t.ID, p.ID)
+	result := hole_tot + hole_pen - h.Par(t.ID, p.ID)
 	cur_tot = cur_tot + result
 	RSS = append(RSS, HoleScoreSummary{
-		Hole:   h.ID,
-		Tee:    t.ID,
-		Pin:    p.ID,
-		Dist:   math.Round(3.281 * Dist(t.Loc, p.Loc)),
-		Par:    h.Par(t.ID, p.ID),
-		Score:  hole_tot,
-		Result: result,
-		Total:  cur_tot,
+		Hole:      h.ID,
+		Tee:       t.ID,
+		Pin:       p.ID,
+		Dist:      InUnits(h.Length(t.ID, p.ID)),
+		Elev:      InUnits(h.Elevation(t.ID, p.ID)),
+		PlaysLike: InUnits(h.PlaysLike(t.ID, p.ID)),
+		Par:       h.Par(t.ID, p.ID),
+		Score:     hole_tot + hole_pen,
+		Result:    result,
+		Total:     cur_tot,
+		Penalty:   hole_pen,
 	})
 
+	return RSS
+}
+
+func (rt Round) DrawSummary() {
+
+	var features []Feature
+
+	all_discs := GetDiscs()
+	used_discs := []Disc{{"UNDEFINED", "blank.png"}, {"BASKET", "basket.png"}}
+
+	for _, d := range all_discs {
+		used_discs = append(used_discs, Disc{
+			ID:    d.Name,
+			Image: d.Image,
+		})
+	}
+
+	c := rt.Course
+
+	RSS := rt.HoleScores()
+
 	for _, h := range c.Holes {
 		for _, t := range h.Tees {
 			geom := Geometry{
@@ -442,6 +690,28 @@ func (rt Round) DrawSummary() {
 		}
 	}
 
+	features = append(features, c.hazardFeatures()...)
+	features = append(features, c.mandoFeatures()...)
+	features = append(features, c.dropZoneFeatures()...)
+
+	for _, hs := range RSS {
+		h := c.GetHole(hs.Hole)
+		geom := Geometry{
+			Type:        "LineString",
+			Coordinates: pathCoords(h.Path(hs.Tee, hs.Pin)),
+		}
+		f := Feature{
+			Type: "Feature",
+			Properties: Properties{
+				Thing: "fairway",
+				Par:   hs.Par,
+				Name:  h.ID + "_" + hs.Tee + "->" + hs.Pin,
+			},
+			Geometry: geom,
+		}
+		features = append(features, f)
+	}
+
 	for _, r := range rt.Data {
 		geom := Geometry{
 			Type:        "Point",
@@ -459,7 +729,7 @@ func (rt Round) DrawSummary() {
 		features = append(features, f)
 	}
 
-	cur_hole = rt.Data[0].HoleID
+	cur_hole := rt.Data[0].HoleID
 	cur_hole_i := 0
 	for i, r := range rt.Data {
 		if i == 0 {
@@ -483,20 +753,28 @@ func (rt Round) DrawSummary() {
 			}
 			features = append(features, f)
 		} else {
+			from := rt.Data.ThrownFrom(i - 1)
 			geom := Geometry{
 				Type:        "LineString",
-				Coordinates: [][]float64{{rt.Data[i-1].Lon, rt.Data[i-1].Lat}, {rt.Data[i].Lon, rt.Data[i].Lat}},
+				Coordinates: [][]float64{{from[1], from[0]}, {rt.Data[i].Lon, rt.Data[i].Lat}},
 			}
 			f := Feature{
 				Type: "Feature",
 				Properties: Properties{
-					Thing:  "throw",
-					Name:   strconv.Itoa(i),
-					Result: RSS[cur_hole_i].Result,
+					Thing:   "throw",
+					Name:    strconv.Itoa(i),
+					Result:  RSS[cur_hole_i].Result,
+					Penalty: rt.Data[i-1].Penalty,
 				},
 				Geometry: geom,
 			}
 			features = append(features, f)
+
+			if rt.Data[i-1].Mando != "" {
+				f.Properties.Thing = "missed_mando"
+				f.Properties.Name = r.HoleID + "_" + rt.Data[i-1].Mando
+				features = append(features, f)
+			}
 		}
 
 	}
@@ -506,10 +784,28 @@ func (rt Round) DrawSummary() {
 		Discs:    used_discs,
 		Features: features,
 		Table:    RSS,
+		Units:    Units(),
 	}
 
 	file, _ := json.MarshalIndent(rgj, "", "	")
-	_ = ioutil.WriteFile("round_vis.json", file, 0644)
+	_ = ioutil.WriteFile(filepath.Join(VisDir(), "round_vis.json"), file, 0644)
+}
+
+func GetRoundIDs() []string {
+	ids, err := DefaultStore().RoundIDs()
+	if err != nil {
+		fmt.Println(err)
+	}
+	return ids
+}
+
+// Reads a saved round from the store
+func LoadRound(roundID string) Round {
+	b, err := DefaultStore().ReadRound(roundID)
+	if err != nil {
+		log.Fatal(roundID + ": " + err.Error())
+	}
+	return readRoundCSV(bytes.NewReader(b))
 }
 
 func ReadRoundCSV(filename string) Round {
@@ -519,27 +815,54 @@ func ReadRoundCSV(filename string) Round {
 	}
 	defer f.Close()
 
+	return readRoundCSV(f)
+}
+
+func readRoundCSV(f io.Reader) Round {
 	rnd := Round{}
 
 	r := csv.NewReader(f)
 	r.FieldsPerRecord = -1
 
-	l, _ := r.Read()
-	rnd.ID = strings.TrimPrefix(l[0], "RoundID: ")
-
-	l, _ = r.Read()
-	rnd.CourseID = strings.TrimPrefix(l[0], "CourseID: ")
-	rnd.Course = GetCourse(rnd.CourseID)
-
-	l, _ = r.Read()
-	rnd.CourseName = strings.TrimPrefix(l[0], "CourseName: ")
+	// "Key: value" header lines run until the column names
+	has_layout := false
+	for {
+		l, err := r.Read()
+		if err != nil {
+			log.Fatal(err)
+		}
+		kv := strings.SplitN(l[0], ": ", 2)
+		if len(kv) < 2 {
+			break
+		}
 
-	l, _ = r.Read()
-	rnd.Notes = strings.TrimPrefix(l[0], "Notes: ")
+		switch kv[0] {
+		case "RoundID":
+			rnd.ID = kv[1]
+		case "CourseID":
+			rnd.CourseID = kv[1]
+		case "CourseName":
+			rnd.CourseName = kv[1]
+		case "CourseRev":
+			rnd.CourseRev = kv[1]
+		case "Layout":
+			has_layout = true
+			rnd.LayoutID = kv[1]
+		case "Notes":
+			rnd.Notes = kv[1]
+		}
+	}
 
-	r.Read()
+	// score against the layout the round was played on
+	if rnd.CourseRev != "" {
+		rnd.Course = GetCourseRevision(rnd.CourseID, rnd.CourseRev)
+	} else {
+		rnd.Course = GetCourseAt(rnd.CourseID, rnd.Time())
+		rnd.CourseRev = rnd.Course.Effective
+	}
 
 	row := 0
+	has_pen := false
 	for {
 		line, err := r.Read()
 		if err == io.EOF {
@@ -555,18 +878,64 @@ func ReadRoundCSV(filename string) Round {
 		lat, _ := strconv.ParseFloat(line[4], 64)
 		lon, _ := strconv.ParseFloat(line[5], 64)
 		disc := line[6]
+		pen := 0
+		if len(line) > 7 {
+			has_pen = true
+			pen, _ = strconv.Atoi(line[7])
+		}
+		mando := ""
+		if len(line) > 8 {
+			mando = line[8]
+		}
+		alt := 0.0
+		has_alt := false
+		if len(line) > 9 && line[9] != "" {
+			alt, _ = strconv.ParseFloat(line[9], 64)
+			has_alt = true
+		}
+		dz := ""
+		if len(line) > 10 {
+			dz = line[10]
+		}
 
 		rnd.Data = append(rnd.Data, RoundRow{
-			RowNum: row,
-			HoleID: hole,
-			TeeID:  tee,
-			PinID:  pin,
-			Lat:    lat,
-			Lon:    lon,
-			Disc:   disc,
+			RowNum:   row,
+			HoleID:   hole,
+			TeeID:    tee,
+			PinID:    pin,
+			Lat:      lat,
+			Lon:      lon,
+			Disc:     disc,
+			Penalty:  pen,
+			Mando:    mando,
+			Alt:      alt,
+			HasAlt:   has_alt,
+			DropZone: dz,
 		})
 
 		row++
 	}
+
+	// rounds saved before penalties were recorded
+	if !has_pen {
+		rnd.Data.assignPenalties(rnd.Course)
+	}
+
+	// and where play went on after each missed mando
+	for i, l := range rnd.Data {
+		if l.Mando == "" {
+			continue
+		}
+		for _, m := range rnd.Course.GetHole(l.HoleID).Mandos {
+			if m.ID == l.Mando {
+				rnd.Data[i].MandoLie = rnd.Course.MandoLie(m, l.Loc())
+			}
+		}
+	}
+
+	if !has_layout {
+		rnd.setLayout()
+	}
+
 	return rnd
 }
@@ -0,0 +1,278 @@
+package rnd
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testTee = Loc{33.0, -117.0}
+
+// One hole, 100 m due north from tee to pin, with a mando halfway that has
+// to be passed on its left
+func testCourse() Course {
+	obj := Destination(testTee, 0, 50)
+	return Course{
+		ID:        "test",
+		Name:      "Test",
+		Loc:       testTee,
+		MandoRule: RETHROW,
+		Holes: []Hole{{
+			ID:     "1",
+			Tees:   []Tee{{ID: "reg", Loc: testTee}},
+			Pins:   []Pin{{ID: "A", Loc: Destination(testTee, 0, 100)}},
+			Pars:   []Par{{Tee: "reg", Pin: "A", Par: 3}},
+			Mandos: []Mando{{ID: "m", Line: []Loc{obj, Destination(obj, 90, 30)}, Side: "left"}},
+		}},
+	}
+}
+
+// A drive that passes right of the mando, an upshot and a putt
+func missedMandoStamps() (Stamps, Loc) {
+	land := Destination(Destination(testTee, 0, 70), 90, 10)
+	return Stamps{
+		{Loc: testTee, Disc: "D"},
+		{Loc: land, Disc: "M"},
+		{Loc: Destination(testTee, 0, 96), Disc: "P"},
+	}, land
+}
+
+func TestMissedMandoSurvivesCleanup(t *testing.T) {
+	c := testCourse()
+	ts, land := missedMandoStamps()
+
+	r := GetRoundOnCourse(ts, "2026-10-18-10-00-00", c)
+	if r.Data[0].Mando != "m" || r.Data[0].Penalty != 1 {
+		t.Fatalf("drive: mando %q penalty %d, want m 1", r.Data[0].Mando, r.Data[0].Penalty)
+	}
+	if d := Dist(r.Data[0].MandoLie, testTee); d > 0.01 {
+		t.Errorf("rethrow lie %.2f m from the tee", d)
+	}
+
+	r.Cleanup()
+	if d := Dist(r.Data[1].Loc(), land); d > 0.01 {
+		t.Errorf("cleanup moved the landing %.2f m", d)
+	}
+	if d := Dist(r.Data.ThrownFrom(1), testTee); d > 0.01 {
+		t.Errorf("upshot thrown from %.2f m off the tee", d)
+	}
+
+	// editing the round splits it up again
+	r.MovePoint(2, Destination(testTee, 0, 97)[0], Destination(testTee, 0, 97)[1])
+	if r.Data[0].Mando != "m" || r.Data[0].Penalty != 1 {
+		t.Errorf("after a move: mando %q penalty %d, want m 1", r.Data[0].Mando, r.Data[0].Penalty)
+	}
+}
+
+func TestRoundCSVPenalties(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemStore())
+	c := testCourse()
+	c.SaveCourse()
+
+	ts, _ := missedMandoStamps()
+	r := GetRoundOnCourse(ts, "2026-10-18-10-00-00", c)
+	r.Cleanup()
+
+	var b bytes.Buffer
+	r.writeCSV(&b)
+	got := readRoundCSV(&b)
+	if got.Data[0].Penalty != 1 || got.Data[0].Mando != "m" || len(got.Data[0].MandoLie) < 2 {
+		t.Errorf("read back penalty %d mando %q lie %v", got.Data[0].Penalty, got.Data[0].Mando, got.Data[0].MandoLie)
+	}
+
+	// rounds saved with a penalty column and nothing after it keep their
+	// penalties as written
+	old := strings.Join([]string{
+		"RoundID: 2026-10-18-10-00-00_-_test",
+		"CourseID: test",
+		"CourseName: Test",
+		"Notes: ",
+		"",
+		"hole,tee,pin,par,lat,lon,disc,penalty",
+		"1,reg,A,3,33.000000,-117.000000,D,2",
+		"1,reg,A,3,33.000900,-117.000000,P,0",
+		"1,reg,A,3,33.000902,-117.000000,BASKET,0",
+	}, "\n")
+	got = readRoundCSV(strings.NewReader(old))
+	if got.Data[0].Penalty != 2 {
+		t.Errorf("8 column round: penalty %d, want 2 as written", got.Data[0].Penalty)
+	}
+}
+
+// A round played at sea level keeps its altitudes, a blank one stays unknown
+func TestRoundCSVAltitudes(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemStore())
+	c := testCourse()
+	c.SaveCourse()
+
+	ts, _ := missedMandoStamps()
+	for i := range ts {
+		ts[i].Loc = Loc{ts[i].Loc[0], ts[i].Loc[1], 0}
+	}
+	r := GetRoundOnCourse(ts, "2026-10-18-10-00-00", c)
+	r.Data[2].HasAlt = false
+
+	var b bytes.Buffer
+	r.writeCSV(&b)
+	got := readRoundCSV(&b)
+	for i, l := range got.Data {
+		if want := i < 2; l.HasAlt != want || l.Alt != 0 {
+			t.Errorf("row %d: altitude %g known %v, want 0 %v", i, l.Alt, l.HasAlt, want)
+		}
+	}
+}
+
+// Holes in a row 100 m apart, each 100 m due north from tee to pin
+func lineCourse(ids ...string) Course {
+	c := Course{ID: "line", Loc: testTee}
+	for k, id := range ids {
+		e := 100 * float64(k)
+		c.Holes = append(c.Holes, Hole{
+			ID:   id,
+			Tees: []Tee{{ID: "reg", Loc: at(e, 0)}},
+			Pins: []Pin{{ID: "A", Loc: at(e, 100)}},
+			Pars: []Par{{Tee: "reg", Pin: "A", Par: 3}},
+		})
+	}
+	return c
+}
+
+// A drive, an upshot and a putt on each hole
+func playHoles(c Course, ids ...string) Stamps {
+	var ts Stamps
+	for _, id := range ids {
+		h := c.GetHole(id)
+		tee, pin := h.Tees[0].Loc, h.Pins[0].Loc
+		ts = append(ts,
+			Stamp{Loc: tee, Disc: "D"},
+			Stamp{Loc: Destination(tee, Bearing(tee, pin), 60), Disc: "M"},
+			Stamp{Loc: Destination(tee, Bearing(tee, pin), 97), Disc: "P"},
+		)
+	}
+	return ts
+}
+
+func playedHoles(rt RoundTable) []string {
+	var ids []string
+	for _, lh := range rt.Played() {
+		ids = append(ids, lh.Hole)
+	}
+	return ids
+}
+
+func TestProcessStampsStart(t *testing.T) {
+	var ids []string
+	for k := 1; k <= 18; k++ {
+		ids = append(ids, strconv.Itoa(k))
+	}
+	c := lineCourse(ids...)
+
+	tests := []struct {
+		name   string
+		played []string
+	}{
+		{"first hole", []string{"1", "2", "3"}},
+		{"mid course", []string{"10", "11", "12"}},
+		{"shotgun", []string{"17", "18", "1", "2"}},
+	}
+	for _, tt := range tests {
+		rt := ProcessStamps(playHoles(c, tt.played...), c, "2026-10-18")
+		if got := playedHoles(rt); !reflect.DeepEqual(got, tt.played) {
+			t.Errorf("%s: played %v, want %v", tt.name, got, tt.played)
+		}
+	}
+}
+
+// Hole 1 ends beside the tees of holes a and b, a's 3 m from where the
+// next drive is thrown and b's 5 m
+func TestProcessStampsSequence(t *testing.T) {
+	c := Course{ID: "seq", Loc: testTee, Holes: []Hole{
+		{ID: "1", Tees: []Tee{{ID: "reg", Loc: at(0, 0)}}, Pins: []Pin{{ID: "A", Loc: at(0, 100)}}},
+		{ID: "a", Tees: []Tee{{ID: "reg", Loc: at(0, 110)}}, Pins: []Pin{{ID: "A", Loc: at(-100, 110)}}},
+		{ID: "b", Tees: []Tee{{ID: "reg", Loc: at(8, 110)}}, Pins: []Pin{{ID: "A", Loc: at(108, 110)}}},
+		{ID: "far", Tees: []Tee{{ID: "reg", Loc: at(500, 0)}}, Pins: []Pin{{ID: "A", Loc: at(500, 100)}}},
+	}}
+	ts := append(playHoles(c, "1"),
+		Stamp{Loc: at(3, 110), Disc: "D"},
+		Stamp{Loc: at(68, 110), Disc: "M"},
+		Stamp{Loc: at(105, 110), Disc: "P"},
+	)
+
+	tests := []struct {
+		name     string
+		sequence []string
+		second   string
+	}{
+		{"hole order", nil, "a"},
+		{"sequence", []string{"1", "b", "a", "far"}, "b"},
+		// the next hole is too far off to be the one teed off
+		{"sequence out of reach", []string{"1", "far", "a", "b"}, "a"},
+	}
+	for _, tt := range tests {
+		c.Sequence = tt.sequence
+		want := []string{"1", tt.second}
+		if got := playedHoles(ProcessStamps(ts, c, "2026-10-18")); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: played %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+// Tees of holes x and y 6 m apart, the first drive is thrown between them
+func TestProcessStampsCloseTees(t *testing.T) {
+	c := Course{ID: "close", Loc: testTee, Holes: []Hole{
+		{ID: "x", Tees: []Tee{{ID: "reg", Loc: at(0, 0)}}, Pins: []Pin{{ID: "A", Loc: at(0, 100)}}},
+		{ID: "y", Tees: []Tee{{ID: "reg", Loc: at(6, 0)}}, Pins: []Pin{{ID: "A", Loc: at(106, 0)}}},
+	}}
+
+	tests := []struct {
+		name   string
+		stamps Stamps
+		hole   string
+	}{
+		{"north", Stamps{{Loc: at(3, 0)}, {Loc: at(1, 60)}, {Loc: at(0, 97)}}, "x"},
+		{"east", Stamps{{Loc: at(3, 0)}, {Loc: at(66, 1)}, {Loc: at(103, 0)}}, "y"},
+	}
+	for _, tt := range tests {
+		rt := ProcessStamps(tt.stamps, c, "2026-10-18")
+		if got := playedHoles(rt); !reflect.DeepEqual(got, []string{tt.hole}) {
+			t.Errorf("%s: played %v, want [%s]", tt.name, got, tt.hole)
+		}
+	}
+}
+
+// A putt into the water beside the pin, re-thrown from a drop zone 5 m from
+// the next tee
+func TestProcessStampsDropZone(t *testing.T) {
+	c := Course{ID: "drop", Loc: testTee, Holes: []Hole{
+		{ID: "1", Tees: []Tee{{ID: "reg", Loc: at(0, 0)}}, Pins: []Pin{{ID: "A", Loc: at(0, 100)}}},
+		{ID: "2", Tees: []Tee{{ID: "reg", Loc: at(5, 70)}}, Pins: []Pin{{ID: "A", Loc: at(105, 70)}}},
+	}}
+	ts := Stamps{{Loc: at(0, 0)}, {Loc: at(0, 95)}, {Loc: at(0, 70)}, {Loc: at(0, 98)}}
+
+	tests := []struct {
+		name      string
+		dropZones []DropZone
+		holes     []string
+		dropZone  string
+		penalties []int
+	}{
+		{"drop zone", []DropZone{{ID: "dz", Loc: at(0, 70)}}, []string{"1", "1", "1", "1"}, "dz", []int{0, 1, 0, 0}},
+		{"no drop zone", nil, []string{"1", "1", "2", "2"}, "", []int{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		c.Holes[0].DropZones = tt.dropZones
+		rt := ProcessStamps(ts, c, "2026-10-18")
+		for i, r := range rt {
+			if r.HoleID != tt.holes[i] || r.Penalty != tt.penalties[i] {
+				t.Errorf("%s: row %d on hole %s with penalty %d, want %s %d", tt.name, i, r.HoleID, r.Penalty, tt.holes[i], tt.penalties[i])
+			}
+		}
+		if rt[2].DropZone != tt.dropZone {
+			t.Errorf("%s: thrown from drop zone %q, want %q", tt.name, rt[2].DropZone, tt.dropZone)
+		}
+	}
+}
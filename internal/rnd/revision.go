@@ -0,0 +1,115 @@
+package rnd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// When a layout saved now takes effect, in the form of a round ID's start so
+// the two compare. Layouts from before this was kept to the second have just
+// a date, and take effect at the start of it.
+func EffectiveNow() string {
+	return time.Now().Format("2006-01-02-15-04-05")
+}
+
+// Old layouts of a course are kept in the store under when they took effect
+// next to the current layout
+func (c Course) SaveCourseRevision() {
+	name := c.Effective
+	if name == "" {
+		name = "initial"
+	}
+
+	file, _ := json.MarshalIndent(c, "", "	")
+	if err := DefaultStore().WriteCourseRevision(c.ID, name, file); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Every known layout of a course, oldest first
+func GetCourseRevisions(courseID string) []Course {
+	var revs []Course
+
+	s := DefaultStore()
+	names, _ := s.CourseRevisions(courseID)
+	for _, name := range names {
+		b, err := s.ReadCourseRevision(courseID, name)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		revs = append(revs, parseCourse(b))
+	}
+
+	// the current file wins over an archived copy of the same revision
+	cur := GetCourse(courseID)
+	if cur.ID != "" {
+		found := false
+		for i, c := range revs {
+			if c.Effective == cur.Effective {
+				revs[i] = cur
+				found = true
+			}
+		}
+		if !found {
+			revs = append(revs, cur)
+		}
+	}
+
+	sort.SliceStable(revs, func(i, j int) bool {
+		return revs[i].Effective < revs[j].Effective
+	})
+
+	return revs
+}
+
+// The layout that was current on date (YYYY-MM-DD, or YYYY-MM-DD-HH-MM-SS
+// for a time that day), or the current layout if no date is given
+func GetCourseAt(courseID string, date string) Course {
+	revs := GetCourseRevisions(courseID)
+	if len(revs) == 0 {
+		return Course{}
+	}
+	if date == "" {
+		return revs[len(revs)-1]
+	}
+
+	best := revs[0]
+	for _, c := range revs {
+		if c.Effective <= date {
+			best = c
+		}
+	}
+	return best
+}
+
+func GetCourseRevision(courseID string, rev string) Course {
+	for _, c := range GetCourseRevisions(courseID) {
+		if c.Effective == rev {
+			return c
+		}
+	}
+
+	fmt.Println("No revision " + rev + " of " + courseID + ", using the current layout")
+	return GetCourse(courseID)
+}
+
+// Whether a course with this ID is in the store
+func CourseExists(courseID string) bool {
+	_, err := DefaultStore().ReadCourse(courseID)
+	return err == nil
+}
+
+// Saves the course over the one in the store with its ID, if there is one,
+// keeping that as a revision so rounds played on it still score against it.
+// The new layout takes effect now.
+func (c Course) ReplaceCourse() {
+	if b, err := DefaultStore().ReadCourse(c.ID); err == nil {
+		parseCourse(b).SaveCourseRevision()
+		c.Effective = EffectiveNow()
+		c.SaveCourseRevision()
+	}
+	c.SaveCourse()
+}
@@ -2,22 +2,53 @@ package rnd
 
 import (
 	"math"
+
+	"github.com/jacobwood27/go-dg-record/internal/geo"
 )
 
+// Lat, lon and, when it was surveyed, altitude in meters
 type Loc []float64
 
-func Dist(l1 Loc, l2 Loc) float64 {
-	R := 6378000.0
+func (l Loc) HasAlt() bool {
+	return len(l) > 2
+}
+
+func (l Loc) Alt() float64 {
+	if !l.HasAlt() {
+		return 0.0
+	}
+	return l[2]
+}
 
-	φ1 := l1[0] * 3.14159 / 180.0
-	φ2 := l2[0] * 3.14159 / 180.0
-	Δφ := (l2[0] - l1[0]) * 3.14159 / 180.0
-	Δλ := (l2[1] - l1[1]) * 3.14159 / 180.0
+// Meters of extra distance a throw plays per meter it climbs
+var PlaysLikeFactor = 3.0
 
-	a := math.Sin(Δφ/2.0)*math.Sin(Δφ/2.0) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(Δλ/2.0)*math.Sin(Δλ/2.0)
-	c := 2.0 * math.Atan2(math.Sqrt(a), math.Sqrt(1.0-a))
+// However far it drops, a throw plays no shorter than this fraction of its
+// length
+var MinPlaysLike = 0.5
 
-	return R * c
+// Elevation adjusted distance for d meters that climbs elev meters
+func PlaysLike(d float64, elev float64) float64 {
+	return math.Max(d+PlaysLikeFactor*elev, MinPlaysLike*d)
+}
+
+// Meters between two locations on the WGS84 ellipsoid
+func Dist(l1 Loc, l2 Loc) float64 {
+	return geo.Distance(l1[0], l1[1], l2[0], l2[1])
+}
+
+// Initial bearing in degrees from l1 to l2, clockwise from north
+func Bearing(l1 Loc, l2 Loc) float64 {
+	return geo.Bearing(l1[0], l1[1], l2[0], l2[1])
+}
+
+// The location d meters from l along bearing brg, at the altitude of l
+func Destination(l Loc, brg float64, d float64) Loc {
+	lat, lon := geo.Destination(l[0], l[1], brg, d)
+	if l.HasAlt() {
+		return Loc{lat, lon, l.Alt()}
+	}
+	return Loc{lat, lon}
 }
 
 type Pin struct {
@@ -36,18 +67,86 @@ type Par struct {
 	Par int    `json:"par"`
 }
 
-type Hole struct {
+type Fairway struct {
+	Tee  string `json:"tee"`
+	Pin  string `json:"pin"`
+	Path []Loc  `json:"path"`
+}
+
+// Hazard types, an island is a safe area inside an ob or hazard area
+const (
+	OB     = "ob"
+	HAZARD = "hazard"
+	ISLAND = "island"
+)
+
+type Hazard struct {
 	ID   string `json:"id"`
-	Tees []Tee  `json:"tees"`
-	Pins []Pin  `json:"pins"`
-	Pars []Par  `json:"pars"`
+	Type string `json:"type"`
+	Poly []Loc  `json:"poly"`
+}
+
+// A mando line runs from the mando object Line[0] out to Line[1], Side is
+// the side of the object ("left" or "right" looking down the throw) the
+// disc has to pass
+type Mando struct {
+	ID       string `json:"id"`
+	Line     []Loc  `json:"line"`
+	Side     string `json:"side"`
+	DropZone Loc    `json:"drop_zone,omitempty"`
+}
+
+// A marked spot play continues from after going ob or missing a mando
+type DropZone struct {
+	ID  string `json:"id"`
+	Loc Loc    `json:"loc"`
+}
+
+// Missed mando rules, both cost a penalty stroke
+const (
+	DROPZONE = "dropzone"
+	RETHROW  = "rethrow"
+)
+
+type Hole struct {
+	ID        string     `json:"id"`
+	Tees      []Tee      `json:"tees"`
+	Pins      []Pin      `json:"pins"`
+	Pars      []Par      `json:"pars"`
+	Fairways  []Fairway  `json:"fairways,omitempty"`
+	Hazards   []Hazard   `json:"hazards,omitempty"`
+	Mandos    []Mando    `json:"mandos,omitempty"`
+	DropZones []DropZone `json:"drop_zones,omitempty"`
+}
+
+// One tee and pin per hole, e.g. "Blue Long"
+type LayoutHole struct {
+	Hole string `json:"hole"`
+	Tee  string `json:"tee"`
+	Pin  string `json:"pin"`
+}
+
+type Layout struct {
+	ID    string       `json:"id"`
+	Name  string       `json:"name"`
+	Holes []LayoutHole `json:"holes"`
 }
 
 type Course struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Loc   Loc    `json:"loc"`
-	Holes []Hole `json:"holes"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Loc       Loc      `json:"loc"`
+	Holes     []Hole   `json:"holes"`
+	MandoRule string   `json:"mando_rule,omitempty"`
+	Effective string   `json:"effective,omitempty"`
+	Layouts   []Layout `json:"layouts,omitempty"`
+	Sequence  []string `json:"sequence,omitempty"`
+
+	// which pin is in play on which dates
+	Rotations []PinRotation `json:"rotations,omitempty"`
+
+	// left by merging two surveys, until settled in edit-course
+	Conflicts []MergeConflict `json:"conflicts,omitempty"`
 }
 
 func (h Hole) Par(tID string, pID string) int {
@@ -59,6 +158,324 @@ func (h Hole) Par(tID string, pID string) int {
 	return 99
 }
 
+// Fairway waypoints between tee and pin, not including either end
+func (h Hole) Waypoints(tID string, pID string) []Loc {
+	for _, f := range h.Fairways {
+		if f.Tee == tID && f.Pin == pID {
+			return f.Path
+		}
+	}
+	return nil
+}
+
+// Full path of play from tee to pin through any fairway waypoints
+func (h Hole) Path(tID string, pID string) []Loc {
+	t := h.GetTee(tID)
+	p := h.GetPin(pID)
+	if len(t.Loc) < 2 || len(p.Loc) < 2 {
+		return nil
+	}
+
+	path := []Loc{t.Loc}
+	path = append(path, h.Waypoints(tID, pID)...)
+	path = append(path, p.Loc)
+	return path
+}
+
+// Playing length in meters, measured along the fairway path
+func (h Hole) Length(tID string, pID string) float64 {
+	path := h.Path(tID, pID)
+
+	d := 0.0
+	for i := 1; i < len(path); i++ {
+		d += Dist(path[i-1], path[i])
+	}
+	return d
+}
+
+// Elevation change in meters from tee to pin, 0 if either is unsurveyed
+func (h Hole) Elevation(tID string, pID string) float64 {
+	t := h.GetTee(tID)
+	p := h.GetPin(pID)
+	if !t.Loc.HasAlt() || !p.Loc.HasAlt() {
+		return 0.0
+	}
+	return p.Loc.Alt() - t.Loc.Alt()
+}
+
+func (h Hole) PlaysLike(tID string, pID string) float64 {
+	return PlaysLike(h.Length(tID, pID), h.Elevation(tID, pID))
+}
+
+func (h *Hole) SetWaypoints(tID string, pID string, path []Loc) {
+	for i, f := range h.Fairways {
+		if f.Tee == tID && f.Pin == pID {
+			if len(path) == 0 {
+				h.Fairways = append(h.Fairways[:i], h.Fairways[i+1:]...)
+			} else {
+				h.Fairways[i].Path = path
+			}
+			return
+		}
+	}
+	if len(path) > 0 {
+		h.Fairways = append(h.Fairways, Fairway{
+			Tee:  tID,
+			Pin:  pID,
+			Path: path,
+		})
+	}
+}
+
+// Inserts a waypoint into the fairway segment closest to l
+func (h *Hole) InsertWaypoint(tID string, pID string, l Loc) {
+	path := h.Path(tID, pID)
+	if path == nil {
+		return
+	}
+
+	best_i := 0
+	best_dist := math.Inf(1)
+	for i := 1; i < len(path); i++ {
+		this_dist := segDist(l, path[i-1], path[i])
+		if this_dist < best_dist {
+			best_i = i - 1
+			best_dist = this_dist
+		}
+	}
+
+	wps := append([]Loc{}, h.Waypoints(tID, pID)...)
+	wps = append(wps[:best_i], append([]Loc{l}, wps[best_i:]...)...)
+	h.SetWaypoints(tID, pID, wps)
+}
+
+// East/north offset in meters of l from o, good over a hole
+func localXY(l Loc, o Loc) (float64, float64) {
+	return geo.NewProjection(o[0], o[1]).Forward(l[0], l[1])
+}
+
+// Approximate distance in meters from l to the segment a-b
+func segDist(l Loc, a Loc, b Loc) float64 {
+	ax, ay := localXY(a, l)
+	bx, by := localXY(b, l)
+	dx, dy := bx-ax, by-ay
+
+	u := 0.0
+	if dx*dx+dy*dy > 0 {
+		u = -(ax*dx + ay*dy) / (dx*dx + dy*dy)
+	}
+	u = math.Max(0, math.Min(1, u))
+
+	return math.Hypot(ax+u*dx, ay+u*dy)
+}
+
+func inPoly(l Loc, poly []Loc) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a := poly[i]
+		b := poly[j]
+		if (a[0] > l[0]) != (b[0] > l[0]) &&
+			l[1] < (b[1]-a[1])*(l[0]-a[0])/(b[0]-a[0])+a[1] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// Penalty strokes for a throw that comes to rest at l
+func (h Hole) Penalty(l Loc) int {
+	pen := 0
+	for _, z := range h.Hazards {
+		if !inPoly(l, z.Poly) {
+			continue
+		}
+		switch z.Type {
+		case ISLAND:
+			return 0
+		case OB, HAZARD:
+			pen = 1
+		}
+	}
+	return pen
+}
+
+// How far in meters from the edge of an ob or hazard area play goes on from
+// after a throw goes in, the point it last crossed in plus GPS error
+var OBLineDist = 3.0
+
+// Whether segment a-b crosses segment c-d
+func segCross(a Loc, b Loc, c Loc, d Loc) bool {
+	orient := func(p Loc, q Loc, r Loc) float64 {
+		return (q[0]-p[0])*(r[1]-p[1]) - (q[1]-p[1])*(r[0]-p[0])
+	}
+	return orient(a, b, c)*orient(a, b, d) < 0 && orient(c, d, a)*orient(c, d, b) < 0
+}
+
+// Distance in meters from l to the edge of poly
+func polyDist(l Loc, poly []Loc) float64 {
+	d := math.Inf(1)
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		d = math.Min(d, segDist(l, poly[j], poly[i]))
+	}
+	return d
+}
+
+// Penalty strokes for a throw from s after which play went on from e. The
+// throw went out if e is in an ob or hazard area, or if e is on the edge of
+// one and the throw carried on into it, as play goes on from where the disc
+// last crossed in.
+func (h Hole) ThrowPenalty(s Loc, e Loc) int {
+	if pen := h.Penalty(e); pen > 0 {
+		return pen
+	}
+	for _, z := range h.Hazards {
+		if z.Type == ISLAND && inPoly(e, z.Poly) {
+			return 0
+		}
+	}
+
+	beyond := Destination(e, Bearing(s, e), OBLineDist)
+	for _, z := range h.Hazards {
+		if z.Type == ISLAND || len(z.Poly) < 3 || polyDist(e, z.Poly) > OBLineDist {
+			continue
+		}
+		if inPoly(beyond, z.Poly) {
+			return 1
+		}
+		for i, j := 0, len(z.Poly)-1; i < len(z.Poly); j, i = i, i+1 {
+			if segCross(s, beyond, z.Poly[j], z.Poly[i]) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// Returns the side ("left" or "right") a throw from s to e passed the mando
+// object on, or "" if it did not cross the mando line
+func (m Mando) Passed(s Loc, e Loc) string {
+	if len(m.Line) < 2 {
+		return ""
+	}
+	a := m.Line[0]
+	sx, sy := localXY(s, a)
+	ex, ey := localXY(e, a)
+	bx, by := localXY(m.Line[1], a)
+	dx, dy := ex-sx, ey-sy
+
+	denom := dx*by - dy*bx
+	if denom == 0 {
+		return ""
+	}
+	t := (-sx*by + sy*bx) / denom
+	u := (-sx*dy + sy*dx) / denom
+	if t < 0 || t > 1 || u < -1 || u > 1 {
+		return ""
+	}
+
+	// crossing point relative to the object
+	xx, xy := sx+t*dx, sy+t*dy
+	if dx*xy-dy*xx > 0 {
+		return "left"
+	}
+	return "right"
+}
+
+// Returns the first mando missed by a throw from s to e, if any
+func (h Hole) MissedMando(s Loc, e Loc) (Mando, bool) {
+	for _, m := range h.Mandos {
+		side := m.Passed(s, e)
+		if side != "" && side != m.Side {
+			return m, true
+		}
+	}
+	return Mando{}, false
+}
+
+// Where play continues from after a throw from lie missed m
+func (c Course) MandoLie(m Mando, lie Loc) Loc {
+	if c.MandoRule == RETHROW || len(m.DropZone) < 2 {
+		return lie
+	}
+	return m.DropZone
+}
+
+func (c Course) GetLayout(lID string) Layout {
+	for _, l := range c.Layouts {
+		if l.ID == lID {
+			return l
+		}
+	}
+	return Layout{}
+}
+
+func (l Layout) Par(c Course) int {
+	par := 0
+	for _, lh := range l.Holes {
+		par += c.GetHole(lh.Hole).Par(lh.Tee, lh.Pin)
+	}
+	return par
+}
+
+// Total playing length in meters
+func (l Layout) Length(c Course) float64 {
+	d := 0.0
+	for _, lh := range l.Holes {
+		d += c.GetHole(lh.Hole).Length(lh.Tee, lh.Pin)
+	}
+	return d
+}
+
+// The layout that best matches the tee and pin played on each hole, holes
+// played from a different tee or to a different pin count against a layout
+func (c Course) InferLayout(played []LayoutHole) Layout {
+	best := Layout{}
+	best_score := 0
+	for _, l := range c.Layouts {
+		score := 0
+		for _, ph := range played {
+			for _, lh := range l.Holes {
+				if lh.Hole != ph.Hole {
+					continue
+				}
+				if lh.Tee == ph.Tee && lh.Pin == ph.Pin {
+					score++
+				} else {
+					score--
+				}
+			}
+		}
+
+		if score > best_score {
+			best = l
+			best_score = score
+		}
+	}
+	return best
+}
+
+// Hole IDs in the order they are played, wrapping from last to first
+func (c Course) PlayOrder() []string {
+	if len(c.Sequence) > 0 {
+		return c.Sequence
+	}
+	var seq []string
+	for _, h := range c.Holes {
+		seq = append(seq, h.ID)
+	}
+	return seq
+}
+
+func (c Course) NextHole(hID string) Hole {
+	seq := c.PlayOrder()
+	for i, id := range seq {
+		if id == hID {
+			return c.GetHole(seq[(i+1)%len(seq)])
+		}
+	}
+	return Hole{}
+}
+
 func (c Course) GetHole(hID string) Hole {
 	for _, h := range c.Holes {
 		if h.ID == hID {
@@ -77,6 +494,27 @@ func (h Hole) GetTee(tID string) Tee {
 	return Tee{}
 }
 
+// The hole's drop zones along with those of its mandos, which take the
+// mando's ID
+func (h Hole) AllDropZones() []DropZone {
+	dzs := append([]DropZone{}, h.DropZones...)
+	for _, m := range h.Mandos {
+		if len(m.DropZone) >= 2 {
+			dzs = append(dzs, DropZone{ID: m.ID, Loc: m.DropZone})
+		}
+	}
+	return dzs
+}
+
+func (h Hole) GetDropZone(dID string) DropZone {
+	for _, d := range h.AllDropZones() {
+		if d.ID == dID {
+			return d
+		}
+	}
+	return DropZone{}
+}
+
 func (h Hole) GetPin(pID string) Pin {
 	for _, p := range h.Pins {
 		if p.ID == pID {
@@ -0,0 +1,68 @@
+package rnd
+
+import "testing"
+
+// The point e meters east and n meters north of testTee
+func at(e float64, n float64) Loc {
+	return Destination(Destination(testTee, 0, n), 90, e)
+}
+
+func TestThrowPenalty(t *testing.T) {
+	h := Hole{
+		ID: "1",
+		Hazards: []Hazard{
+			{ID: "pond", Type: OB, Poly: []Loc{at(20, 40), at(60, 40), at(60, 80), at(20, 80)}},
+			{ID: "green", Type: ISLAND, Poly: []Loc{at(35, 55), at(45, 55), at(45, 65), at(35, 65)}},
+		},
+	}
+
+	cases := []struct {
+		name string
+		s, e Loc
+		want int
+	}{
+		{"at rest in the pond", testTee, at(30, 60), 1},
+		{"playing on from where it crossed in", at(0, 30), at(19, 45), 1},
+		{"short of the pond on a line into it", at(0, 50), at(18, 50), 1},
+		{"beside the pond going along it", at(18, 0), at(18, 60), 0},
+		{"nowhere near", testTee, at(0, 60), 0},
+		{"over the pond and well past", testTee, at(80, 70), 0},
+		{"on the island", testTee, at(40, 60), 0},
+	}
+	for _, c := range cases {
+		if got := h.ThrowPenalty(c.s, c.e); got != c.want {
+			t.Errorf("%s: penalty %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPlaysLike(t *testing.T) {
+	cases := []struct {
+		name    string
+		d, elev float64
+		want    float64
+	}{
+		{"flat", 100, 0, 100},
+		{"uphill", 100, 10, 130},
+		{"downhill", 100, -10, 70},
+		{"steep drop", 60, -20, 30},
+		{"cliff", 60, -40, 30},
+	}
+	for _, c := range cases {
+		if got := PlaysLike(c.d, c.elev); got != c.want {
+			t.Errorf("%s: plays like %g, want %g", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRoundRowSeaLevel(t *testing.T) {
+	rr := RoundRow{Lat: 33, Lon: -117}
+	rr.setLoc(Loc{33, -117, 0})
+	if l := rr.Loc(); !l.HasAlt() || l.Alt() != 0 {
+		t.Errorf("sea level fix came back as %v", l)
+	}
+	rr.setLoc(Loc{33, -117})
+	if l := rr.Loc(); l.HasAlt() {
+		t.Errorf("fix with no altitude came back as %v", l)
+	}
+}
@@ -0,0 +1,148 @@
+package rnd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The pin in play on each hole from From to To, both "2006-01-02" and
+// inclusive. An empty To runs on until the next rotation.
+type PinRotation struct {
+	From string            `json:"from"`
+	To   string            `json:"to,omitempty"`
+	Pins map[string]string `json:"pins"`
+}
+
+func (pr PinRotation) covers(date string) bool {
+	return date >= pr.From && (pr.To == "" || date <= pr.To)
+}
+
+// The pin the rotation has in play on a hole on date. Later rotations win
+// where they overlap.
+func (c Course) ActivePin(hID string, date string) (string, bool) {
+	if len(date) < 10 {
+		return "", false
+	}
+	date = date[:10]
+
+	pID, ok := "", false
+	for _, pr := range c.Rotations {
+		if p := pr.Pins[hID]; p != "" && pr.covers(date) {
+			pID, ok = p, true
+		}
+	}
+	return pID, ok
+}
+
+// The pin in play on the hole on date if the rotation has one, and the pin
+// nearest l otherwise
+func inferPinOn(l Loc, h Hole, c Course, date string) Pin {
+	if pID, ok := c.ActivePin(h.ID, date); ok {
+		if p := h.GetPin(pID); p.ID != "" {
+			return p
+		}
+	}
+	return inferPin(l, h)
+}
+
+// Starts a rotation on from, running until the next one starts and cutting
+// short the one before it. Holes keep the pins that were in play.
+func (c *Course) AddRotation(from string) error {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return err
+	}
+
+	pr := PinRotation{From: from, Pins: map[string]string{}}
+	for _, h := range c.Holes {
+		if pID, ok := c.ActivePin(h.ID, from); ok {
+			pr.Pins[h.ID] = pID
+		} else if len(h.Pins) > 0 {
+			pr.Pins[h.ID] = h.Pins[0].ID
+		}
+	}
+
+	i := len(c.Rotations)
+	for k, r := range c.Rotations {
+		if r.From == from {
+			return fmt.Errorf("a rotation already starts on %s", from)
+		}
+		if r.From > from && k < i {
+			i = k
+		}
+	}
+	if i > 0 && (c.Rotations[i-1].To == "" || c.Rotations[i-1].To >= from) {
+		c.Rotations[i-1].To = start.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	if i < len(c.Rotations) {
+		next, err := time.Parse("2006-01-02", c.Rotations[i].From)
+		if err == nil {
+			pr.To = next.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+	}
+
+	c.Rotations = append(c.Rotations[:i], append([]PinRotation{pr}, c.Rotations[i:]...)...)
+	return nil
+}
+
+func (c *Course) SetRotationPin(i int, hID string, pID string) {
+	if i < 0 || i >= len(c.Rotations) {
+		return
+	}
+	if c.Rotations[i].Pins == nil {
+		c.Rotations[i].Pins = map[string]string{}
+	}
+	if pID == "" {
+		delete(c.Rotations[i].Pins, hID)
+		return
+	}
+	c.Rotations[i].Pins[hID] = pID
+}
+
+func (c *Course) SetRotationDates(i int, from string, to string) {
+	if i < 0 || i >= len(c.Rotations) {
+		return
+	}
+	c.Rotations[i].From = from
+	c.Rotations[i].To = to
+}
+
+func (c *Course) DeleteRotation(i int) {
+	if i < 0 || i >= len(c.Rotations) {
+		return
+	}
+	c.Rotations = append(c.Rotations[:i], c.Rotations[i+1:]...)
+}
+
+func (c Course) validateRotations() []string {
+	var warns []string
+	for _, pr := range c.Rotations {
+		pre := "rotation from " + pr.From + ": "
+		if _, err := time.Parse("2006-01-02", pr.From); err != nil {
+			warns = append(warns, pre+"bad start date")
+		}
+		if pr.To != "" {
+			if _, err := time.Parse("2006-01-02", pr.To); err != nil {
+				warns = append(warns, pre+"bad end date")
+			} else if pr.To < pr.From {
+				warns = append(warns, pre+"ends before it starts")
+			}
+		}
+		var hIDs []string
+		for hID := range pr.Pins {
+			hIDs = append(hIDs, hID)
+		}
+		sort.Strings(hIDs)
+		for _, hID := range hIDs {
+			pID := pr.Pins[hID]
+			h := c.GetHole(hID)
+			if h.ID == "" {
+				warns = append(warns, pre+"unknown hole "+hID)
+			} else if h.GetPin(pID).ID == "" {
+				warns = append(warns, pre+"hole "+hID+" has no pin "+pID)
+			}
+		}
+	}
+	return warns
+}
@@ -0,0 +1,102 @@
+package rnd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestActivePin(t *testing.T) {
+	c := Course{Rotations: []PinRotation{
+		{From: "2026-01-01", To: "2026-03-31", Pins: map[string]string{"1": "A", "2": "A"}},
+		{From: "2026-03-01", Pins: map[string]string{"1": "B"}},
+	}}
+
+	tests := []struct {
+		hole string
+		date string
+		pin  string
+		ok   bool
+	}{
+		{"1", "2025-12-31", "", false},
+		{"1", "2026-01-01", "A", true},
+		{"1", "2026-02-01-10-00-00", "A", true},
+		// the later rotation wins where they overlap
+		{"1", "2026-03-15", "B", true},
+		{"2", "2026-03-31", "A", true},
+		{"2", "2026-04-01", "", false},
+		{"1", "2027-01-01", "B", true},
+		{"1", "2026", "", false},
+	}
+	for _, tt := range tests {
+		if pin, ok := c.ActivePin(tt.hole, tt.date); pin != tt.pin || ok != tt.ok {
+			t.Errorf("hole %s on %s: %q %v, want %q %v", tt.hole, tt.date, pin, ok, tt.pin, tt.ok)
+		}
+	}
+}
+
+func TestAddRotation(t *testing.T) {
+	c := Course{Holes: []Hole{
+		{ID: "1", Pins: []Pin{{ID: "A"}, {ID: "B"}}},
+		{ID: "2", Pins: []Pin{{ID: "A"}, {ID: "B"}}},
+	}}
+
+	if err := c.AddRotation("2026-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRotationPin(0, "2", "B")
+	if err := c.AddRotation("2026-06-01"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRotationPin(1, "1", "B")
+	// in between the two, cut short by the one after it
+	if err := c.AddRotation("2026-03-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []PinRotation{
+		{From: "2026-01-01", To: "2026-02-28", Pins: map[string]string{"1": "A", "2": "B"}},
+		{From: "2026-03-01", To: "2026-05-31", Pins: map[string]string{"1": "A", "2": "B"}},
+		{From: "2026-06-01", Pins: map[string]string{"1": "B", "2": "B"}},
+	}
+	if !reflect.DeepEqual(c.Rotations, want) {
+		t.Errorf("rotations %+v, want %+v", c.Rotations, want)
+	}
+
+	if err := c.AddRotation("2026-03-01"); err == nil {
+		t.Error("added a second rotation starting on 2026-03-01")
+	}
+	if err := c.AddRotation("March"); err == nil {
+		t.Error("added a rotation with a bad date")
+	}
+	if len(c.Rotations) != 3 {
+		t.Errorf("%d rotations after the failed adds, want 3", len(c.Rotations))
+	}
+}
+
+// The putt is nearer pin A, the rotation has B in play from October
+func TestProcessStampsRotation(t *testing.T) {
+	c := Course{ID: "rot", Loc: testTee,
+		Holes: []Hole{{
+			ID:   "1",
+			Tees: []Tee{{ID: "reg", Loc: at(0, 0)}},
+			Pins: []Pin{{ID: "A", Loc: at(0, 100)}, {ID: "B", Loc: at(8, 100)}},
+		}},
+		Rotations: []PinRotation{{From: "2026-10-01", Pins: map[string]string{"1": "B"}}},
+	}
+	ts := Stamps{{Loc: at(0, 0)}, {Loc: at(2, 60)}, {Loc: at(2, 99)}}
+
+	tests := []struct {
+		date string
+		pin  string
+	}{
+		{"2026-09-30-10-00-00", "A"},
+		{"2026-10-18-10-00-00", "B"},
+	}
+	for _, tt := range tests {
+		for i, r := range ProcessStamps(ts, c, tt.date) {
+			if r.PinID != tt.pin {
+				t.Errorf("%s: row %d to pin %s, want %s", tt.date, i, r.PinID, tt.pin)
+			}
+		}
+	}
+}
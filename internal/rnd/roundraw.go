@@ -1,11 +1,11 @@
 package rnd
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"strconv"
 )
 
@@ -15,39 +15,55 @@ type Stamp struct {
 }
 type Stamps []Stamp
 
+// Saves the throws to the store before they're split into holes
 func (ts Stamps) WriteRoundRawCSV(fID string) {
-	f, err := os.Create(fID + "_raw.csv")
-	if err != nil {
+	var b bytes.Buffer
+	ts.writeRawCSV(&b)
+	if err := DefaultStore().WriteRawRound(fID, b.Bytes()); err != nil {
 		fmt.Println(err)
 	}
-	defer f.Close()
+}
 
+func (ts Stamps) writeRawCSV(f io.Writer) {
 	w := csv.NewWriter(f)
 	defer w.Flush()
-	w.Write([]string{"lat", "lon", "disc"})
+	w.Write([]string{"lat", "lon", "disc", "alt"})
 
 	for _, t := range ts {
+		alt := ""
+		if t.Loc.HasAlt() {
+			alt = fmt.Sprintf("%f", t.Loc.Alt())
+		}
 		w.Write([]string{
 			fmt.Sprintf("%f", t.Loc[0]),
 			fmt.Sprintf("%f", t.Loc[1]),
 			t.Disc,
+			alt,
 		})
 	}
 }
 
 func ReadRoundRawCSV(fileID string) Stamps {
-	f, err := os.Open(fileID + "_raw.csv")
+	b, err := DefaultStore().ReadRawRound(fileID)
 	if err != nil {
-		fmt.Println(err)
+		log.Fatal(fileID + ": " + err.Error())
 	}
-	defer f.Close()
 
-	r := csv.NewReader(f)
-	_, err = r.Read() // Burn the header line
+	r := csv.NewReader(bytes.NewReader(b))
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// older raw files have no alt column, and some carry extra survey columns
+	alt_col := -1
+	for i, h := range header {
+		if h == "alt" {
+			alt_col = i
+		}
+	}
+
 	var ts []Stamp
 	for {
 		line, err := r.Read()
@@ -61,8 +77,13 @@ func ReadRoundRawCSV(fileID string) Stamps {
 		lat, _ := strconv.ParseFloat(line[0], 64)
 		lon, _ := strconv.ParseFloat(line[1], 64)
 		disc := line[2]
+		loc := Loc{lat, lon}
+		if alt_col >= 0 && alt_col < len(line) && line[alt_col] != "" {
+			alt, _ := strconv.ParseFloat(line[alt_col], 64)
+			loc = append(loc, alt)
+		}
 		ts = append(ts, Stamp{
-			Loc:  Loc{lat, lon},
+			Loc:  loc,
 			Disc: disc})
 	}
 
@@ -0,0 +1,315 @@
+package rnd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/jacobwood27/go-dg-record/internal/geo"
+)
+
+// Smoothing applied to a recorded track before throws are interpolated
+// along it. Stationary averaging is done before the Kalman smoother when
+// both are asked for.
+const (
+	NOSMOOTH   = "none"
+	KALMAN     = "kalman"
+	STATIONARY = "stationary"
+)
+
+const SmoothEnv = "DISCGOLF_SMOOTH"
+
+var smoothFlag string
+
+func init() {
+	flag.StringVar(&smoothFlag, "smooth", "", "track smoothing, none, kalman, stationary or kalman,stationary (default $"+SmoothEnv+", then Smooth: in the config file, then none)")
+}
+
+// Tuning for the smoothers
+var (
+	// Horizontal error in meters of a fix with an HDOP of 1
+	UERE = 5.0
+
+	// Error in meters of fixes with no HDOP or accuracy
+	DefaultAccuracy = 8.0
+
+	// Random walking acceleration in m/s^2 the Kalman filter allows for
+	KalmanAccel = 0.5
+
+	// Fixes that stay this many meters, or twice their accuracy if that's
+	// more, from where they average out to for StationaryTime seconds or
+	// more are taken as standing still
+	StationaryRadius = 5.0
+	StationaryTime   = 20.0
+
+	// Longest stretch in seconds checked as one, longer ones are split. Each
+	// fix added is checked against all the ones before it in the stretch, so
+	// this keeps a long wait on a tee from taking quadratic time.
+	MaxDwellTime = 60.0
+)
+
+// The smoothers to run, from the -smooth flag, the DISCGOLF_SMOOTH
+// environment variable or the config file, in that order
+func Smoothing() []string {
+	s := smoothFlag
+	if s == "" {
+		s = os.Getenv(SmoothEnv)
+	}
+	if s == "" {
+		s = readConfig(ConfigFile())["Smooth"]
+	}
+
+	var kalman, stationary bool
+	for _, m := range strings.Split(s, ",") {
+		switch strings.TrimSpace(m) {
+		case "", NOSMOOTH:
+		case KALMAN:
+			kalman = true
+		case STATIONARY:
+			stationary = true
+		default:
+			fmt.Println("Unknown smoothing " + m + ", ignoring it")
+		}
+	}
+
+	var sm []string
+	if stationary {
+		sm = append(sm, STATIONARY)
+	}
+	if kalman {
+		sm = append(sm, KALMAN)
+	}
+	return sm
+}
+
+// Horizontal error of a fix in meters
+func (l locstamp) accuracy() float64 {
+	if l.acc > 0 {
+		return l.acc
+	}
+	return DefaultAccuracy
+}
+
+func (l locstamp) weight() float64 {
+	return 1 / (l.accuracy() * l.accuracy())
+}
+
+func smoothTrack(locs locstamps) locstamps {
+	for _, s := range Smoothing() {
+		switch s {
+		case STATIONARY:
+			locs = locs.stationary()
+		case KALMAN:
+			locs = locs.kalman()
+		}
+	}
+	return locs
+}
+
+// A stretch of fixes, first up to but not including last, that stay near
+// where they average out to
+type dwell struct {
+	first  int
+	last   int
+	lat    float64
+	lon    float64
+	alt    float64
+	hasAlt bool
+}
+
+// The stretches of minTime seconds or more over which every fix stays within
+// radius meters, or accFactor times its accuracy if that's more, of the
+// stretch's average. Fixes are weighted by their accuracy. Standing still for
+// more than MaxDwellTime seconds gives back-to-back stretches.
+func (locs locstamps) dwells(radius float64, minTime float64, accFactor float64) []dwell {
+	if len(locs) == 0 {
+		return nil
+	}
+	p := geo.NewProjection(locs[0].lat, locs[0].lon)
+
+	xs := make([]float64, len(locs))
+	ys := make([]float64, len(locs))
+	for i, l := range locs {
+		xs[i], ys[i] = p.Forward(l.lat, l.lon)
+	}
+
+	var ds []dwell
+	for i := 0; i < len(locs); {
+		var sw, sx, sy, sa, swa float64
+		add := func(k int) {
+			w := locs[k].weight()
+			sw += w
+			sx += w * xs[k]
+			sy += w * ys[k]
+			if locs[k].hasAlt {
+				sa += w * locs[k].alt
+				swa += w
+			}
+		}
+
+		// grow the stretch while every fix stays near its average
+		add(i)
+		j := i + 1
+		for ; j < len(locs); j++ {
+			if locs[j].time.Sub(locs[i].time).Seconds() > MaxDwellTime {
+				break
+			}
+			w := locs[j].weight()
+			mx, my := (sx+w*xs[j])/(sw+w), (sy+w*ys[j])/(sw+w)
+			near := true
+			for k := i; k <= j && near; k++ {
+				near = math.Hypot(xs[k]-mx, ys[k]-my) <= math.Max(radius, accFactor*locs[k].accuracy())
+			}
+			if !near {
+				break
+			}
+			add(j)
+		}
+
+		if locs[j-1].time.Sub(locs[i].time).Seconds() < minTime {
+			i++
+			continue
+		}
+
+		d := dwell{first: i, last: j}
+		d.lat, d.lon = p.Inverse(sx/sw, sy/sw)
+		if swa > 0 {
+			d.alt, d.hasAlt = sa/swa, true
+		}
+		ds = append(ds, d)
+		i = j
+	}
+
+	return ds
+}
+
+// Replaces each stretch of fixes taken standing still with their average,
+// weighting each fix by its accuracy
+func (locs locstamps) stationary() locstamps {
+	out := append(locstamps{}, locs...)
+	for _, d := range locs.dwells(StationaryRadius, StationaryTime, 2) {
+		for k := d.first; k < d.last; k++ {
+			out[k].lat, out[k].lon = d.lat, d.lon
+			if d.hasAlt && out[k].hasAlt {
+				out[k].alt = d.alt
+			}
+		}
+	}
+	return out
+}
+
+// Kalman filters and RTS smooths one coordinate with a constant velocity
+// model, given the times in seconds and the variance of each measurement
+func kalmanAxis(t []float64, z []float64, r []float64, q float64) []float64 {
+	n := len(z)
+	type state struct {
+		x, v          float64
+		pxx, pxv, pvv float64
+	}
+	filt := make([]state, n)
+	pred := make([]state, n)
+
+	s := state{x: z[0], pxx: r[0], pvv: 25}
+	for k := 0; k < n; k++ {
+		if k > 0 {
+			dt := t[k] - t[k-1]
+			s = state{
+				x:   s.x + dt*s.v,
+				v:   s.v,
+				pxx: s.pxx + 2*dt*s.pxv + dt*dt*s.pvv + q*dt*dt*dt/3,
+				pxv: s.pxv + dt*s.pvv + q*dt*dt/2,
+				pvv: s.pvv + q*dt,
+			}
+		}
+		pred[k] = s
+
+		// measure the position
+		kx := s.pxx / (s.pxx + r[k])
+		kv := s.pxv / (s.pxx + r[k])
+		y := z[k] - s.x
+		s = state{
+			x:   s.x + kx*y,
+			v:   s.v + kv*y,
+			pxx: (1 - kx) * s.pxx,
+			pxv: (1 - kx) * s.pxv,
+			pvv: s.pvv - kv*s.pxv,
+		}
+		filt[k] = s
+	}
+
+	// and back again
+	out := make([]float64, n)
+	xs, vs := filt[n-1].x, filt[n-1].v
+	out[n-1] = xs
+	for k := n - 2; k >= 0; k-- {
+		dt := t[k+1] - t[k]
+		f, p := filt[k], pred[k+1]
+
+		// C = P_f F' P_p^-1
+		a, b := f.pxx+dt*f.pxv, f.pxv
+		c, d := f.pxv+dt*f.pvv, f.pvv
+		det := p.pxx*p.pvv - p.pxv*p.pxv
+		if det <= 0 {
+			xs, vs = f.x, f.v
+			out[k] = xs
+			continue
+		}
+		i11, i12, i22 := p.pvv/det, -p.pxv/det, p.pxx/det
+		c11, c12 := a*i11+b*i12, a*i12+b*i22
+		c21, c22 := c*i11+d*i12, c*i12+d*i22
+
+		dx, dv := xs-p.x, vs-p.v
+		xs, vs = f.x+c11*dx+c12*dv, f.v+c21*dx+c22*dv
+		out[k] = xs
+	}
+	return out
+}
+
+// Smooths the track with a constant velocity Kalman filter and smoother,
+// trusting each fix according to its accuracy
+func (locs locstamps) kalman() locstamps {
+	if len(locs) < 2 {
+		return locs
+	}
+	out := append(locstamps{}, locs...)
+	p := geo.NewProjection(locs[0].lat, locs[0].lon)
+	q := KalmanAccel * KalmanAccel
+
+	n := len(locs)
+	t := make([]float64, n)
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	r := make([]float64, n)
+	for i, l := range locs {
+		t[i] = l.time.Sub(locs[0].time).Seconds()
+		xs[i], ys[i] = p.Forward(l.lat, l.lon)
+		r[i] = math.Pow(l.accuracy(), 2)
+	}
+
+	sx := kalmanAxis(t, xs, r, q)
+	sy := kalmanAxis(t, ys, r, q)
+	for i := range out {
+		out[i].lat, out[i].lon = p.Inverse(sx[i], sy[i])
+	}
+
+	// altitude is worse than position, about half again
+	var ta, za, ra []float64
+	var idx []int
+	for i, l := range locs {
+		if l.hasAlt {
+			ta = append(ta, t[i])
+			za = append(za, l.alt)
+			ra = append(ra, 2.25*r[i])
+			idx = append(idx, i)
+		}
+	}
+	if len(za) > 1 {
+		for k, a := range kalmanAxis(ta, za, ra, q) {
+			out[idx[k]].alt = a
+		}
+	}
+
+	return out
+}
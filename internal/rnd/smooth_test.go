@@ -0,0 +1,102 @@
+package rnd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var trackStart = time.Date(2026, 10, 18, 17, 0, 0, 0, time.UTC)
+
+// A fix a second along a path given in meters east and north of testTee,
+// with gaussian noise of sd meters that the fixes claim as their accuracy.
+// Returns the fixes and where they should have been.
+func noisyTrack(n int, sd float64, seed int64, path func(s float64) (float64, float64)) (locstamps, []Loc) {
+	rng := rand.New(rand.NewSource(seed))
+	var locs locstamps
+	var truth []Loc
+	for i := 0; i < n; i++ {
+		e, nn := path(float64(i))
+		l := at(e+sd*rng.NormFloat64(), nn+sd*rng.NormFloat64())
+		locs = append(locs, locstamp{time: trackStart.Add(time.Duration(i) * time.Second), lat: l[0], lon: l[1], acc: sd})
+		truth = append(truth, at(e, nn))
+	}
+	return locs, truth
+}
+
+func rmsError(locs locstamps, truth []Loc) float64 {
+	s := 0.0
+	for i, l := range locs {
+		s += math.Pow(Dist(Loc{l.lat, l.lon}, truth[i]), 2)
+	}
+	return math.Sqrt(s / float64(len(locs)))
+}
+
+func TestKalmanReducesJitter(t *testing.T) {
+	// walking north, stopping to throw, then walking east
+	path := func(s float64) (float64, float64) {
+		switch {
+		case s < 100:
+			return 0, 1.2 * s
+		case s < 130:
+			return 0, 120
+		}
+		return 1.2 * (s - 130), 120
+	}
+	for seed := int64(1); seed <= 3; seed++ {
+		locs, truth := noisyTrack(250, 4, seed, path)
+		raw, smooth := rmsError(locs, truth), rmsError(locs.kalman(), truth)
+		if smooth > 0.6*raw {
+			t.Errorf("seed %d: smoothed %.2f m rms from the path, raw %.2f m", seed, smooth, raw)
+		}
+	}
+}
+
+func TestStationaryCollapses(t *testing.T) {
+	// standing on the tee for a minute then walking off north
+	locs, truth := noisyTrack(120, 1.5, 1, func(s float64) (float64, float64) {
+		return 0, 1.3 * math.Max(0, s-60)
+	})
+	st := locs.stationary()
+
+	for i := 1; i < 50; i++ {
+		if st[i].lat != st[0].lat || st[i].lon != st[0].lon {
+			t.Fatalf("fix %d standing still not averaged with the first", i)
+		}
+	}
+	if d := Dist(Loc{st[0].lat, st[0].lon}, truth[0]); d > 1 {
+		t.Errorf("standing still averaged to %.2f m from where it was", d)
+	}
+	for i := 80; i < len(st); i++ {
+		if st[i] != locs[i] {
+			t.Errorf("walking fix %d moved", i)
+		}
+	}
+}
+
+func TestDwellsLongWait(t *testing.T) {
+	// ten minutes waiting on a tee
+	locs, truth := noisyTrack(600, 1, 1, func(s float64) (float64, float64) { return 0, 0 })
+
+	ds := locs.dwells(StopRadius, StopTime, 0)
+	covered := 0
+	for _, d := range ds {
+		if span := locs[d.last-1].time.Sub(locs[d.first].time).Seconds(); span > MaxDwellTime {
+			t.Errorf("stretch of %.0f s, longer than %.0f s", span, MaxDwellTime)
+		}
+		covered += d.last - d.first
+	}
+	if covered < 500 {
+		t.Errorf("only %d of %d fixes found standing still", covered, len(locs))
+	}
+
+	// a stop is still the one wait
+	stops := locs.stops()
+	if len(stops) != 1 {
+		t.Fatalf("%d stops, want 1", len(stops))
+	}
+	if d := Dist(Loc{stops[0].lat, stops[0].lon}, truth[0]); d > 1 {
+		t.Errorf("stop %.2f m from where it was", d)
+	}
+}
@@ -0,0 +1,67 @@
+package rnd
+
+import "log"
+
+// Tuning for finding throws from where a track stops
+var (
+	// Staying within StopRadius meters of one spot for StopTime seconds or
+	// more is taken as lining up a throw. Walking slowly covers more than
+	// this, even when the fixes are only good to several meters.
+	StopRadius = 3.0
+	StopTime   = 10.0
+
+	// Stops closer than StopMergeDist meters with less than StopMergeTime
+	// seconds between them are one stop the fixes wandered out of
+	StopMergeDist = 3.0
+	StopMergeTime = 15.0
+)
+
+// The places the player stood still. They're looked for on the Kalman
+// smoothed track whatever the smoothing, as the raw fixes wander about by
+// more than StopRadius.
+func (locs locstamps) stops() []dwell {
+	ks := locs.kalman()
+	ds := ks.dwells(StopRadius, StopTime, 0)
+
+	var merged []dwell
+	for _, d := range ds {
+		if n := len(merged); n > 0 {
+			p := merged[n-1]
+			gap := locs[d.first].time.Sub(locs[p.last-1].time).Seconds()
+			if gap < StopMergeTime && Dist(Loc{p.lat, p.lon}, Loc{d.lat, d.lon}) < StopMergeDist {
+				d.first = p.first
+				merged[n-1] = d
+				continue
+			}
+		}
+		merged = append(merged, d)
+	}
+	return merged
+}
+
+// Like GetRoundRaw for when there is only the recording, with a throw of an
+// unknown disc from wherever the track stops. Expect to clean the round up
+// in the editor.
+func GetRoundStops(rec string) (Stamps, string) {
+	locs, _ := parseRecording(rec)
+	locs = smoothTrack(locs)
+
+	ds := locs.stops()
+	if len(ds) == 0 {
+		log.Fatal(rec + ": no stops found")
+	}
+
+	var ts Stamps
+	for _, d := range ds {
+		loc := Loc{d.lat, d.lon}
+		if d.hasAlt {
+			loc = append(loc, d.alt)
+		}
+		ts = append(ts, Stamp{Loc: loc, Disc: "UNDEFINED"})
+	}
+
+	// the first throw is made as the first stop ends
+	fID := locs[ds[0].last-1].time.Local().Format("2006-01-02-15-04-05")
+
+	return ts, fID
+}
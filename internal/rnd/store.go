@@ -0,0 +1,398 @@
+package rnd
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A Store holds the courses, rounds, discs and stats shared by the tools.
+// Records are passed around as the bytes of their file format: course JSON,
+// round CSV, disc CSV and whatever the stats files are.
+type Store interface {
+	CourseIDs() ([]string, error)
+	ReadCourse(id string) ([]byte, error)
+	WriteCourse(id string, b []byte) error
+
+	// Old layouts of a course keyed by their effective date
+	CourseRevisions(id string) ([]string, error)
+	ReadCourseRevision(id string, rev string) ([]byte, error)
+	WriteCourseRevision(id string, rev string, b []byte) error
+
+	RoundIDs() ([]string, error)
+	ReadRound(id string) ([]byte, error)
+	WriteRound(id string, b []byte) error
+
+	// The discs in the bag and the catalog of every disc they refer to
+	ReadDiscs() ([]byte, error)
+	ReadDiscCatalog() ([]byte, error)
+	WriteDiscCatalog(b []byte) error
+
+	// Other names recording apps give the discs in the bag
+	ReadDiscAliases() ([]byte, error)
+
+	ReadStats(name string) ([]byte, error)
+	WriteStats(name string, b []byte) error
+
+	// Throws located along a recording before they're split into holes,
+	// keyed by the time of the first throw
+	ReadRawRound(fID string) ([]byte, error)
+	WriteRawRound(fID string, b []byte) error
+
+	// Courses written out for other apps, e.g. "<id>.gpx"
+	WriteExport(name string, b []byte) error
+}
+
+var ErrNotFound = errors.New("not found in store")
+
+const HomeEnv = "DISCGOLF_HOME"
+
+var homeFlag string
+
+func init() {
+	flag.StringVar(&homeFlag, "home", "", "data directory (default $"+HomeEnv+", then Home: in the config file, then ~/.discgolf)")
+}
+
+// Where the config file that may name the data directory lives
+func ConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "discgolf", "config")
+}
+
+// Reads "Key: value" lines from the config file
+func readConfig(filename string) map[string]string {
+	conf := map[string]string{}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return conf
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) < 2 {
+			continue
+		}
+		conf[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return conf
+}
+
+// The data directory, taken from the -home flag, the DISCGOLF_HOME
+// environment variable or the config file, in that order, and ~/.discgolf
+// otherwise
+func DataHome() string {
+	if homeFlag != "" {
+		return homeFlag
+	}
+	if h := os.Getenv(HomeEnv); h != "" {
+		return h
+	}
+	if h := readConfig(ConfigFile())["Home"]; h != "" {
+		return h
+	}
+
+	homedir, _ := os.UserHomeDir()
+	return filepath.Join(homedir, ".discgolf")
+}
+
+var store Store
+
+// The store every package level function reads from, opened on the data
+// directory the first time it is needed
+func DefaultStore() Store {
+	if store == nil {
+		store = NewFSStore(DataHome())
+	}
+	return store
+}
+
+// Swaps the store out, e.g. for a MemStore in tests
+func SetStore(s Store) {
+	store = s
+	courseIndex = nil
+}
+
+// Scratch directory the editors keep their map data in
+func VisDir() string {
+	d := filepath.Join(os.TempDir(), "discgolf")
+	os.MkdirAll(d, 0755)
+	return d
+}
+
+// FSStore keeps everything in a directory laid out as
+//
+//	courses/<id>.json
+//	courses/<id>/<rev>.json
+//	rounds/<id>/<id>.csv
+//	discs/discs.csv
+//	discs/aliases.csv
+//	discs.csv
+//	stats/<name>
+//	raw/<fID>_raw.csv
+//	exports/<name>
+type FSStore struct {
+	Root string
+}
+
+func NewFSStore(root string) FSStore {
+	return FSStore{Root: root}
+}
+
+func (s FSStore) read(elem ...string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(append([]string{s.Root}, elem...)...))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+func (s FSStore) write(b []byte, elem ...string) error {
+	fname := filepath.Join(append([]string{s.Root}, elem...)...)
+	if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fname, b, 0644)
+}
+
+// Names of the entries in dir, either the .json files or the directories
+func (s FSStore) list(dir string, dirs bool) ([]string, error) {
+	files, err := os.ReadDir(filepath.Join(s.Root, dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if dirs && f.IsDir() {
+			names = append(names, f.Name())
+		}
+		if !dirs && !f.IsDir() && filepath.Ext(f.Name()) == ".json" {
+			names = append(names, strings.TrimSuffix(f.Name(), ".json"))
+		}
+	}
+	return names, nil
+}
+
+func (s FSStore) CourseIDs() ([]string, error) {
+	return s.list("courses", false)
+}
+
+func (s FSStore) ReadCourse(id string) ([]byte, error) {
+	return s.read("courses", id+".json")
+}
+
+func (s FSStore) WriteCourse(id string, b []byte) error {
+	return s.write(b, "courses", id+".json")
+}
+
+func (s FSStore) CourseRevisions(id string) ([]string, error) {
+	return s.list(filepath.Join("courses", id), false)
+}
+
+func (s FSStore) ReadCourseRevision(id string, rev string) ([]byte, error) {
+	return s.read("courses", id, rev+".json")
+}
+
+func (s FSStore) WriteCourseRevision(id string, rev string, b []byte) error {
+	return s.write(b, "courses", id, rev+".json")
+}
+
+func (s FSStore) RoundIDs() ([]string, error) {
+	return s.list("rounds", true)
+}
+
+func (s FSStore) ReadRound(id string) ([]byte, error) {
+	return s.read("rounds", id, id+".csv")
+}
+
+func (s FSStore) WriteRound(id string, b []byte) error {
+	return s.write(b, "rounds", id, id+".csv")
+}
+
+func (s FSStore) ReadDiscs() ([]byte, error) {
+	return s.read("discs", "discs.csv")
+}
+
+func (s FSStore) ReadDiscCatalog() ([]byte, error) {
+	return s.read("discs.csv")
+}
+
+func (s FSStore) WriteDiscCatalog(b []byte) error {
+	return s.write(b, "discs.csv")
+}
+
+func (s FSStore) ReadDiscAliases() ([]byte, error) {
+	return s.read("discs", "aliases.csv")
+}
+
+func (s FSStore) ReadStats(name string) ([]byte, error) {
+	return s.read("stats", name)
+}
+
+func (s FSStore) WriteStats(name string, b []byte) error {
+	return s.write(b, "stats", name)
+}
+
+func (s FSStore) ReadRawRound(fID string) ([]byte, error) {
+	return s.read("raw", fID+"_raw.csv")
+}
+
+func (s FSStore) WriteRawRound(fID string, b []byte) error {
+	return s.write(b, "raw", fID+"_raw.csv")
+}
+
+func (s FSStore) WriteExport(name string, b []byte) error {
+	return s.write(b, "exports", name)
+}
+
+// MemStore keeps everything in memory. Files are keyed by the path they
+// would have in an FSStore.
+type MemStore struct {
+	mu    sync.Mutex
+	Files map[string][]byte
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{Files: map[string][]byte{}}
+}
+
+func (s *MemStore) read(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.Files[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (s *MemStore) write(key string, b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files[key] = append([]byte(nil), b...)
+	return nil
+}
+
+// Names between prefix and suffix with no further / in them
+func (s *MemStore) list(prefix string, suffix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := map[string]bool{}
+	var names []string
+	for k := range s.Files {
+		if !strings.HasPrefix(k, prefix) || !strings.HasSuffix(k, suffix) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(k, prefix), suffix)
+		if name == "" || strings.Contains(name, "/") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *MemStore) CourseIDs() ([]string, error) {
+	return s.list("courses/", ".json"), nil
+}
+
+func (s *MemStore) ReadCourse(id string) ([]byte, error) {
+	return s.read("courses/" + id + ".json")
+}
+
+func (s *MemStore) WriteCourse(id string, b []byte) error {
+	return s.write("courses/"+id+".json", b)
+}
+
+func (s *MemStore) CourseRevisions(id string) ([]string, error) {
+	return s.list("courses/"+id+"/", ".json"), nil
+}
+
+func (s *MemStore) ReadCourseRevision(id string, rev string) ([]byte, error) {
+	return s.read("courses/" + id + "/" + rev + ".json")
+}
+
+func (s *MemStore) WriteCourseRevision(id string, rev string, b []byte) error {
+	return s.write("courses/"+id+"/"+rev+".json", b)
+}
+
+func (s *MemStore) RoundIDs() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for k := range s.Files {
+		// rounds/<id>/<id>.csv
+		kv := strings.Split(strings.TrimPrefix(k, "rounds/"), "/")
+		if strings.HasPrefix(k, "rounds/") && len(kv) == 2 && kv[1] == kv[0]+".csv" {
+			ids = append(ids, kv[0])
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *MemStore) ReadRound(id string) ([]byte, error) {
+	return s.read("rounds/" + id + "/" + id + ".csv")
+}
+
+func (s *MemStore) WriteRound(id string, b []byte) error {
+	return s.write("rounds/"+id+"/"+id+".csv", b)
+}
+
+func (s *MemStore) ReadDiscs() ([]byte, error) {
+	return s.read("discs/discs.csv")
+}
+
+func (s *MemStore) ReadDiscCatalog() ([]byte, error) {
+	return s.read("discs.csv")
+}
+
+func (s *MemStore) WriteDiscCatalog(b []byte) error {
+	return s.write("discs.csv", b)
+}
+
+func (s *MemStore) ReadDiscAliases() ([]byte, error) {
+	return s.read("discs/aliases.csv")
+}
+
+func (s *MemStore) ReadStats(name string) ([]byte, error) {
+	return s.read("stats/" + name)
+}
+
+func (s *MemStore) WriteStats(name string, b []byte) error {
+	return s.write("stats/"+name, b)
+}
+
+func (s *MemStore) ReadRawRound(fID string) ([]byte, error) {
+	return s.read("raw/" + fID + "_raw.csv")
+}
+
+func (s *MemStore) WriteRawRound(fID string, b []byte) error {
+	return s.write("raw/"+fID+"_raw.csv", b)
+}
+
+func (s *MemStore) WriteExport(name string, b []byte) error {
+	return s.write("exports/"+name, b)
+}
@@ -0,0 +1,165 @@
+package rnd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"mem": NewMemStore(),
+		"fs":  FSStore{Root: t.TempDir()},
+	}
+}
+
+func TestStoreCourses(t *testing.T) {
+	defer SetStore(nil)
+	for name, s := range testStores(t) {
+		SetStore(s)
+
+		if CourseExists("test") {
+			t.Errorf("%s: empty store has a course", name)
+		}
+
+		c := testCourse()
+		c.Effective = "2020-01-01"
+		c.SaveCourse()
+		got := GetCourse("test")
+		if got.Name != c.Name || len(got.Holes) != 1 || got.Holes[0].Pars[0].Par != 3 {
+			t.Errorf("%s: read back %+v", name, got)
+		}
+		if ids := GetCourseIDs(); !reflect.DeepEqual(ids, []string{"test"}) {
+			t.Errorf("%s: course IDs %v", name, ids)
+		}
+
+		// replacing keeps the old layout as a revision
+		c.Holes[0].Pars[0].Par = 4
+		c.ReplaceCourse()
+		today := time.Now().Format("2006-01-02")
+		revs := GetCourseRevisions("test")
+		if len(revs) != 2 || revs[0].Effective != "2020-01-01" || !strings.HasPrefix(revs[1].Effective, today) {
+			t.Fatalf("%s: revisions %d", name, len(revs))
+		}
+		if p := GetCourseAt("test", "2021-06-01").Holes[0].Pars[0].Par; p != 3 {
+			t.Errorf("%s: par %d on the old layout, want 3", name, p)
+		}
+		if p := GetCourse("test").Holes[0].Pars[0].Par; p != 4 {
+			t.Errorf("%s: par %d on the current layout, want 4", name, p)
+		}
+	}
+}
+
+// Two layouts saved the same day, each round scores against the one that
+// was current when it was played
+func TestCourseRevisionsSameDay(t *testing.T) {
+	defer SetStore(nil)
+	SetStore(NewMemStore())
+
+	c := testCourse()
+	c.Effective = "2026-10-18"
+	c.SaveCourseRevision()
+	c.Holes[0].Pars[0].Par = 4
+	c.Effective = "2026-10-18-12-00-00"
+	c.SaveCourseRevision()
+	c.SaveCourse()
+
+	tests := []struct {
+		when string
+		par  int
+	}{
+		{"2026-10-18-08-00-00", 3},
+		{"2026-10-18-11-59-59", 3},
+		{"2026-10-18-12-00-00", 4},
+		{"2026-10-19", 4},
+	}
+	for _, tt := range tests {
+		if p := GetCourseAt("test", tt.when).Holes[0].Par("reg", "A"); p != tt.par {
+			t.Errorf("%s: par %d, want %d", tt.when, p, tt.par)
+		}
+	}
+
+	ts, _ := missedMandoStamps()
+	r := GetRoundOnCourse(ts, "2026-10-18-10-00-00", GetCourse("test"))
+	if r.CourseRev != "2026-10-18" || r.Course.Holes[0].Par("reg", "A") != 3 {
+		t.Errorf("morning round on layout %q", r.CourseRev)
+	}
+}
+
+func TestStoreRounds(t *testing.T) {
+	defer SetStore(nil)
+	for name, s := range testStores(t) {
+		SetStore(s)
+		c := testCourse()
+		c.SaveCourse()
+
+		if ids := GetRoundIDs(); len(ids) != 0 {
+			t.Errorf("%s: empty store has rounds %v", name, ids)
+		}
+
+		ts, _ := missedMandoStamps()
+		for _, fID := range []string{"2026-10-18-10-00-00", "2026-10-19-10-00-00"} {
+			GetRoundOnCourse(ts, fID, c).WriteCSV()
+		}
+		want := []string{"2026-10-18-10-00-00_-_test", "2026-10-19-10-00-00_-_test"}
+		if ids := GetRoundIDs(); !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: round IDs %v, want %v", name, ids, want)
+		}
+
+		r := LoadRound(want[1])
+		if r.ID != want[1] || r.CourseID != "test" || len(r.Data) != len(ts) {
+			t.Errorf("%s: loaded %s on %s with %d rows", name, r.ID, r.CourseID, len(r.Data))
+		}
+	}
+}
+
+func TestImportData(t *testing.T) {
+	defer SetStore(nil)
+
+	old := t.TempDir()
+	src := FSStore{Root: old}
+	for _, id := range []string{"a", "b"} {
+		if err := src.WriteCourse(id, []byte(`{"id": "`+id+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.WriteCourseRevision("a", "2020-01-01", []byte(`{"id": "a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.WriteDiscCatalog([]byte("id,name\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewMemStore()
+	SetStore(s)
+	s.WriteCourse("b", []byte(`{"id": "b", "name": "mine"}`))
+
+	copied, err := ImportData(old, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"courses/a", "courses/a/2020-01-01", "discs.csv"}
+	if !reflect.DeepEqual(copied, want) {
+		t.Errorf("copied %v, want %v", copied, want)
+	}
+	if GetCourse("b").Name != "mine" {
+		t.Error("a course already in the store was replaced")
+	}
+	if len(GetCourseRevisions("a")) != 1 {
+		t.Error("revision of a not imported")
+	}
+
+	if _, err := ImportData(old, true); err != nil {
+		t.Fatal(err)
+	}
+	if GetCourse("b").Name != "" {
+		t.Error("-force left the stored course")
+	}
+
+	if _, err := os.Stat(filepath.Join(old, "courses", "a.json")); err != nil {
+		t.Error("import removed the source")
+	}
+}
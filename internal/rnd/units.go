@@ -0,0 +1,72 @@
+package rnd
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Unit systems for the distances written to summaries and stats
+const (
+	FEET   = "ft"
+	METERS = "m"
+)
+
+const UnitsEnv = "DISCGOLF_UNITS"
+
+var unitsFlag string
+
+var units string
+
+func init() {
+	flag.StringVar(&unitsFlag, "units", "", "distance units, ft or m (default $"+UnitsEnv+", then Units: in the config file, then ft)")
+}
+
+// The distance units, taken from the -units flag, the DISCGOLF_UNITS
+// environment variable or the config file, in that order, and feet otherwise
+func Units() string {
+	if units == "" {
+		units = parseUnits()
+	}
+	return units
+}
+
+func parseUnits() string {
+	u := unitsFlag
+	if u == "" {
+		u = os.Getenv(UnitsEnv)
+	}
+	if u == "" {
+		u = readConfig(ConfigFile())["Units"]
+	}
+
+	switch u {
+	case "", FEET, "feet":
+		return FEET
+	case METERS, "meters", "metres":
+		return METERS
+	}
+
+	fmt.Println("Unknown units " + u + ", using " + FEET)
+	return FEET
+}
+
+// Sets the units for the rest of the run, overriding flag and config
+func SetUnits(u string) {
+	unitsFlag = u
+	units = parseUnits()
+}
+
+// Meters expressed in the current units, rounded to a whole number
+func InUnits(m float64) float64 {
+	if Units() == METERS {
+		return math.Round(m)
+	}
+	return math.Round(m * 3.28084)
+}
+
+// A column header naming its units, e.g. "Dist (ft)"
+func WithUnits(name string) string {
+	return name + " (" + Units() + ")"
+}
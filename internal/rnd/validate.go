@@ -0,0 +1,159 @@
+package rnd
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Limits used when checking a course for mistakes, in meters
+var (
+	MinHoleLength = 20.0
+	MaxHoleLength = 400.0
+	TeeOnPinDist  = 5.0
+	CourseLocDist = 1000.0
+)
+
+// Problems with a course layout that would give wrong scores or odd stats
+func (c Course) Validate() []string {
+	var warns []string
+
+	hole_ids := make(map[string]int)
+	last_num := -1
+	for _, h := range c.Holes {
+		hole_ids[h.ID]++
+		if hole_ids[h.ID] == 2 {
+			warns = append(warns, "duplicate hole "+h.ID)
+		}
+
+		// with an explicit sequence the order of the holes doesn't matter
+		if n, err := strconv.Atoi(h.ID); err == nil && len(c.Sequence) == 0 {
+			if n < last_num {
+				warns = append(warns, fmt.Sprintf("hole %s is out of order after hole %d", h.ID, last_num))
+			}
+			last_num = n
+		}
+
+		warns = append(warns, h.validate()...)
+	}
+
+	seq_ids := make(map[string]int)
+	for _, id := range c.Sequence {
+		seq_ids[id]++
+		if seq_ids[id] == 2 {
+			warns = append(warns, "hole "+id+" is in the sequence twice")
+		}
+		if hole_ids[id] == 0 {
+			warns = append(warns, "sequence has unknown hole "+id)
+		}
+	}
+	for _, h := range c.Holes {
+		if len(c.Sequence) > 0 && seq_ids[h.ID] == 0 {
+			warns = append(warns, "hole "+h.ID+" is missing from the sequence")
+		}
+	}
+
+	warns = append(warns, c.validateRotations()...)
+
+	if len(c.Conflicts) > 0 {
+		warns = append(warns, fmt.Sprintf("%d unresolved merge conflicts", len(c.Conflicts)))
+	}
+
+	if len(c.Loc) < 2 {
+		warns = append(warns, "course has no location")
+	} else {
+		near := -1.0
+		for _, h := range c.Holes {
+			for _, t := range h.Tees {
+				if len(t.Loc) < 2 {
+					continue
+				}
+				if d := Dist(c.Loc, t.Loc); near < 0 || d < near {
+					near = d
+				}
+			}
+		}
+		if near > CourseLocDist {
+			warns = append(warns, fmt.Sprintf("course location is %.0f m from the nearest tee", near))
+		}
+	}
+
+	return warns
+}
+
+func (h Hole) validate() []string {
+	var warns []string
+	pre := "hole " + h.ID + ": "
+
+	tee_ids := make(map[string]int)
+	for _, t := range h.Tees {
+		tee_ids[t.ID]++
+		if tee_ids[t.ID] == 2 {
+			warns = append(warns, pre+"duplicate tee "+t.ID)
+		}
+		if len(t.Loc) < 2 {
+			warns = append(warns, pre+"tee "+t.ID+" has no location")
+		}
+	}
+
+	pin_ids := make(map[string]int)
+	for _, p := range h.Pins {
+		pin_ids[p.ID]++
+		if pin_ids[p.ID] == 2 {
+			warns = append(warns, pre+"duplicate pin "+p.ID)
+		}
+		if len(p.Loc) < 2 {
+			warns = append(warns, pre+"pin "+p.ID+" has no location")
+		}
+	}
+
+	dz_ids := make(map[string]int)
+	for _, d := range h.AllDropZones() {
+		dz_ids[d.ID]++
+		if dz_ids[d.ID] == 2 {
+			warns = append(warns, pre+"duplicate drop zone "+d.ID)
+		}
+		if len(d.Loc) < 2 {
+			warns = append(warns, pre+"drop zone "+d.ID+" has no location")
+		}
+	}
+
+	if len(h.Tees) == 0 {
+		warns = append(warns, pre+"no tees")
+	}
+	if len(h.Pins) == 0 {
+		warns = append(warns, pre+"no pins")
+	}
+
+	for _, t := range h.Tees {
+		for _, p := range h.Pins {
+			n := 0
+			for _, par := range h.Pars {
+				if par.Tee == t.ID && par.Pin == p.ID {
+					n++
+				}
+			}
+			if n == 0 {
+				warns = append(warns, pre+"missing par for "+t.ID+"->"+p.ID)
+			} else if n > 1 {
+				warns = append(warns, pre+"duplicate par for "+t.ID+"->"+p.ID)
+			}
+
+			if len(t.Loc) < 2 || len(p.Loc) < 2 {
+				continue
+			}
+			if d := Dist(t.Loc, p.Loc); d < TeeOnPinDist {
+				warns = append(warns, fmt.Sprintf("%stee %s is %.1f m from pin %s", pre, t.ID, d, p.ID))
+			} else if l := h.Length(t.ID, p.ID); l < MinHoleLength || l > MaxHoleLength {
+				warns = append(warns, fmt.Sprintf("%s%s->%s is %.0f m long", pre, t.ID, p.ID, l))
+			}
+		}
+	}
+
+	for _, par := range h.Pars {
+		if tee_ids[par.Tee] == 0 || pin_ids[par.Pin] == 0 {
+			warns = append(warns, pre+"par for unknown "+par.Tee+"->"+par.Pin)
+		}
+	}
+
+	return warns
+}